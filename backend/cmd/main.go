@@ -2,35 +2,50 @@ package main
 
 import (
 	"backend/internal/auth"
+	"backend/internal/avatar"
+	"backend/internal/chat"
 	"backend/internal/game"
+	"backend/internal/history"
+	"backend/internal/lobby"
 	"backend/internal/middleware"
+	"backend/internal/moderation"
 	"backend/internal/room"
 	"backend/internal/spotify"
 	"backend/internal/store"
 	"backend/internal/ws"
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
-func roomRouterHandler(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
+func roomRouterHandler(rooms *room.Handler, games *game.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		parts := strings.Split(path, "/")
 
-	if len(parts) == 3 {
-		room.GetRoomHandler(w, r)
-	} else if len(parts) == 4 && parts[3] == "questions" {
+		if len(parts) == 3 {
+			rooms.GetRoomHandler(w, r)
+		} else if len(parts) == 4 && parts[3] == "questions" {
 
-		game.GetQuestionsHandler(w, r)
-	} else if len(parts) == 4 && parts[3] == "scoreboard" {
-		game.GetScoreboardHandler(w, r)
-	} else if len(parts) == 4 && parts[3] == "next-question" {
-		game.GetNextQuestionHandler(w, r)
-	} else {
-		http.Error(w, "Invalid room route", http.StatusNotFound)
+			games.GetQuestionsHandler(w, r)
+		} else if len(parts) == 4 && parts[3] == "scoreboard" {
+			games.GetScoreboardHandler(w, r)
+		} else if len(parts) == 4 && parts[3] == "summary" {
+			games.GetSummaryHandler(w, r)
+		} else if len(parts) == 4 && parts[3] == "search" {
+			rooms.SearchTracksHandler(w, r)
+		} else if len(parts) == 4 && parts[3] == "link" {
+			rooms.JoinLinkHandler(w, r)
+		} else if len(parts) == 4 && (parts[3] == "qr.png" || parts[3] == "qr.svg") {
+			rooms.QRCodeHandler(w, r)
+		} else {
+			http.Error(w, "Invalid room route", http.StatusNotFound)
+		}
 	}
 }
 
@@ -40,9 +55,27 @@ func main() {
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
-	go ws.GlobalHub.Run()
+	hub := ws.NewHub()
+	feed := lobby.NewFeed()
+	repo := lobby.NewRepository(store.NewRedisRepository(store.InitRedis()), feed)
+	go repo.PruneEvery(context.Background(), time.Minute)
+	hosts := room.NewHostMonitor(repo, hub, room.HostGracePeriod())
+	filter := moderation.FilterFromEnv()
+	chats := chat.NewHandler(repo, hub, filter)
+	hub.OnPresence(hosts.Presence)
+	hub.OnPresence(chats.Presence)
+	go hub.Run()
 
-	store.InitRedis()
+	historyStore := history.InitSQLite()
+	defer historyStore.Close()
+
+	spotifyClient := spotify.NewClient(spotify.ConfigFromEnv())
+	rooms := room.NewHandler(repo, hub, room.ConfigFromEnv(), filter, spotifyClient)
+	games := game.NewHandler(repo, hub, historyStore, spotifyClient)
+	authHandler := auth.NewHandler(repo, spotifyClient)
+	search := spotify.NewHandler(spotifyClient)
+	players := history.NewHandler(historyStore)
+	lobbies := lobby.NewHandler(repo, feed)
 
 	r := http.NewServeMux()
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -50,15 +83,33 @@ func main() {
 		w.Write([]byte("Hello from root: " + r.URL.Path))
 	})
 
-	r.HandleFunc("/create-room", room.CreateRoomHandler)
-	r.HandleFunc("/join-room", room.JoinRoomHandler)
-	r.HandleFunc("/room/", roomRouterHandler)
-	r.HandleFunc("/auth/callback", auth.AuthCallbackHandler)
-	r.HandleFunc("/start-game", game.StartGameHandler)
-	r.HandleFunc("/submit-answer", game.SubmitAnswerHandler)
-	r.HandleFunc("/ws/", ws.WSHandler)
-	r.HandleFunc("/auth/validate-token", auth.EnsureValidTokenHandler)
-	r.HandleFunc("/spotify/search", spotify.SearchSpotifyHandler)
+	r.HandleFunc("/create-room", rooms.CreateRoomHandler)
+	r.HandleFunc("/join-room", rooms.JoinRoomHandler)
+	r.HandleFunc("/room-settings", rooms.UpdateSettingsHandler)
+	r.HandleFunc("/rename-player", rooms.RenamePlayerHandler)
+	r.HandleFunc("/leave-room", rooms.LeaveRoomHandler)
+	r.HandleFunc("/kick-player", rooms.KickPlayerHandler)
+	r.HandleFunc("/ban-player", rooms.BanPlayerHandler)
+	r.HandleFunc("/submit-song", rooms.SubmitSongHandler)
+	r.HandleFunc("/remove-song", rooms.RemoveSongHandler)
+	r.HandleFunc("/room/", roomRouterHandler(rooms, games))
+	r.HandleFunc("/lobbies", lobbies.ListHandler)
+	r.HandleFunc("/lobbies/feed", lobbies.FeedHandler)
+	r.HandleFunc("/auth/callback", authHandler.AuthCallbackHandler)
+	r.HandleFunc("/prepare-game", games.PrepareGameHandler)
+	r.HandleFunc("/start-game", games.StartGameHandler)
+	r.HandleFunc("/submit-answer", games.SubmitAnswerHandler)
+	r.HandleFunc("/play-again", games.PlayAgainHandler)
+	r.HandleFunc("/close-room", games.CloseRoomHandler)
+	hub.Handle("ready", rooms.ReadyMessage)
+	hub.Handle("chat", chats.ChatMessage)
+	hub.Handle("reaction", chats.ReactionMessage)
+	hub.Admit(rooms.AdmitPlayer)
+	r.HandleFunc("/ws/", hub.WSHandler)
+	r.HandleFunc("/auth/validate-token", authHandler.EnsureValidTokenHandler)
+	r.HandleFunc("/spotify/search", search.SearchSpotifyHandler)
+	r.HandleFunc("/players/", players.PlayerRouterHandler)
+	r.HandleFunc("/avatars/", avatar.Handler)
 	handler := middleware.EnableCORS(r)
 	log.Println("Server on :8081")
 	http.ListenAndServe(":8081", handler)
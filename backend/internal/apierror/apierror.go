@@ -0,0 +1,26 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Response is the JSON body of an API error. Code is a stable, machine-readable
+// identifier the frontend can switch on; Message is meant for humans.
+type Response struct {
+	Code    string `json:"error"`
+	Message string `json:"message"`
+}
+
+// Write responds with the given status code and a JSON error body:
+//
+//	{
+//	  "error": "invalid_state",
+//	  "message": "cannot move room from in-round to generating"
+//	}
+func Write(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Response{Code: code, Message: message})
+}
@@ -1,37 +1,30 @@
 package auth
 
 import (
+	"backend/internal/model"
+	"backend/internal/spotify"
 	"backend/internal/store"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
-	"strings"
 	"time"
 )
 
-type AuthCallbackRequest struct {
-	Code string `json:"code"`
-}
-
-type TokenResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int    `json:"expires_in"`
+// Handler serves the Spotify OAuth endpoints and keeps user tokens in the repository.
+type Handler struct {
+	repo    store.Repository
+	spotify *spotify.Client
 }
 
-type TokenData struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int64  `json:"expires_in"`
+// NewHandler returns an auth Handler using the given repository and Spotify client.
+func NewHandler(repo store.Repository, client *spotify.Client) *Handler {
+	return &Handler{repo: repo, spotify: client}
 }
 
-type SpotifyMeResponse struct {
-	ID string `json:"id"`
+type AuthCallbackRequest struct {
+	Code string `json:"code"`
 }
 
 // AuthCallbackHandler handles HTTP POST requests to /auth/callback.
@@ -69,7 +62,7 @@ type SpotifyMeResponse struct {
 //
 // In case of an error (e.g. invalid code, Spotify API failure, or Redis write failure),
 // responds with an appropriate HTTP error status.
-func AuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("AuthCallbackHandler hit!")
 	var body struct {
 		Code string `json:"code"`
@@ -80,82 +73,32 @@ func AuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	clientID := os.Getenv("SPOTIFY_CLIENT_ID")
-	clientSecret := os.Getenv("SPOTIFY_CLIENT_SECRET")
-	redirectURI := os.Getenv("SPOTIFY_REDIRECT_URI")
-
-	data := url.Values{}
-	data.Set("grant_type", "authorization_code")
-	data.Set("code", body.Code)
-	data.Set("redirect_uri", redirectURI)
-
-	req, err := http.NewRequest("POST", "https://accounts.spotify.com/api/token", strings.NewReader(data.Encode()))
-	if err != nil {
-		log.Println("Failed to build token request:", err)
-		http.Error(w, "Token request error", http.StatusInternalServerError)
+	tokenRes, err := h.spotify.ExchangeCode(r.Context(), body.Code)
+	var apiErr *spotify.APIError
+	if errors.As(err, &apiErr) {
+		log.Printf("Token exchange failed: %v", err)
+		http.Error(w, "Failed to exchange token", http.StatusBadRequest)
 		return
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", clientID, clientSecret)))
-	req.Header.Set("Authorization", "Basic "+auth)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
 	if err != nil {
 		log.Println("Failed to send token request:", err)
 		http.Error(w, "Token exchange failed", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		log.Printf("Token exchange failed (status %d): %s", resp.StatusCode, string(bodyBytes))
-		http.Error(w, "Failed to exchange token", http.StatusBadRequest)
-		return
-	}
-
-	var tokenRes TokenResponse
-	if err := json.NewDecoder(resp.Body).Decode(&tokenRes); err != nil {
-		log.Println("Failed to decode token response:", err)
-		http.Error(w, "Token decode error", http.StatusInternalServerError)
-		return
-	}
-
-	userReq, _ := http.NewRequest("GET", "https://api.spotify.com/v1/me", nil)
-	userReq.Header.Set("Authorization", "Bearer "+tokenRes.AccessToken)
-
-	userResp, err := client.Do(userReq)
+	me, err := h.spotify.Me(r.Context(), tokenRes.AccessToken)
 	if err != nil {
 		log.Println("Failed to get user profile:", err)
-		http.Error(w, "User profile fetch failed", http.StatusInternalServerError)
-		return
-	}
-	defer userResp.Body.Close()
-
-	if userResp.StatusCode != 200 {
-		bodyBytes, _ := io.ReadAll(userResp.Body)
-		log.Printf("Failed to get user profile (status %d): %s", userResp.StatusCode, string(bodyBytes))
 		http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
 		return
 	}
 
-	var me struct {
-		ID string `json:"id"`
+	tokenData := model.UserToken{
+		AccessToken:  tokenRes.AccessToken,
+		RefreshToken: tokenRes.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(tokenRes.ExpiresIn) * time.Second).Unix(),
 	}
-	if err := json.NewDecoder(userResp.Body).Decode(&me); err != nil {
-		log.Println("Failed to decode user profile:", err)
-		http.Error(w, "User decode error", http.StatusInternalServerError)
-		return
-	}
-
-	tokenData, _ := json.Marshal(map[string]any{
-		"access_token":  tokenRes.AccessToken,
-		"refresh_token": tokenRes.RefreshToken,
-		"expires_at":    time.Now().Add(time.Duration(tokenRes.ExpiresIn) * time.Second).Unix(),
-	})
-	userKey := "user:" + me.ID
-	if err := store.Client.Set(store.Ctx, userKey, tokenData, 0).Err(); err != nil {
+	if err := h.repo.SaveUserToken(r.Context(), me.ID, tokenData); err != nil {
 		log.Println(" Failed to save token data:", err)
 		http.Error(w, "Failed to save user", http.StatusInternalServerError)
 		return
@@ -187,10 +130,10 @@ func AuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 //     "user:{clientId}" → {
 //     "access_token": "...",
 //     "refresh_token": "...",
-//     "expires_in": 1719440000
+//     "expires_at": 1719440000
 //     }
 //
-//  3. If the access token has not expired (based on current time vs. `expires_in`),
+//  3. If the access token has not expired (based on current time vs. `expires_at`),
 //     returns the existing access token.
 //
 //  4. If the token has expired, sends a POST request to Spotify's token endpoint
@@ -208,7 +151,7 @@ func AuthCallbackHandler(w http.ResponseWriter, r *http.Request) {
 //
 // In case of errors (e.g. Redis read failure, expired refresh token, Spotify API failure),
 // responds with an appropriate HTTP error code such as 400, 404, 500, or 502.
-func EnsureValidTokenHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EnsureValidTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var request struct {
 		ClientID string `json:"clientId"`
 		Token    string `json:"token"`
@@ -220,77 +163,42 @@ func EnsureValidTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userKey := "user:" + request.ClientID
-	redisData, err := store.Client.Get(store.Ctx, userKey).Result()
-	if err != nil {
-		log.Println("Failed to get user token from Redis:", err)
+	tokenData, err := h.repo.GetUserToken(r.Context(), request.ClientID)
+	if errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "User token not found", http.StatusNotFound)
 		return
 	}
-
-	var tokenData TokenData
-	if err := json.Unmarshal([]byte(redisData), &tokenData); err != nil {
-		log.Println("Failed to parse token data:", err)
+	if err != nil {
+		log.Println("Failed to get user token:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if tokenData.ExpiresIn > time.Now().Unix() {
+	if tokenData.ExpiresAt > time.Now().Unix() {
 		json.NewEncoder(w).Encode(map[string]string{
 			"access_token": tokenData.AccessToken,
 		})
 		return
 	}
 
-	clientID := os.Getenv("SPOTIFY_CLIENT_ID")
-	clientSecret := os.Getenv("SPOTIFY_CLIENT_SECRET")
-
-	form := url.Values{}
-	form.Set("grant_type", "refresh_token")
-	form.Set("refresh_token", tokenData.RefreshToken)
-
-	req, err := http.NewRequest("POST", "https://accounts.spotify.com/api/token", strings.NewReader(form.Encode()))
-	if err != nil {
-		log.Println("Failed to create refresh request:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	refreshRes, err := h.spotify.RefreshToken(r.Context(), tokenData.RefreshToken)
+	var apiErr *spotify.APIError
+	if errors.As(err, &apiErr) {
+		log.Printf("Spotify token refresh failed: %v", err)
+		http.Error(w, "Failed to refresh token", http.StatusUnauthorized)
 		return
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	auth := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", clientID, clientSecret)))
-	req.Header.Set("Authorization", "Basic "+auth)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
 	if err != nil {
 		log.Println("Failed to call Spotify token endpoint:", err)
 		http.Error(w, "Spotify token request failed", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Spotify token refresh failed: %s\n", string(body))
-		http.Error(w, "Failed to refresh token", http.StatusUnauthorized)
-		return
-	}
-
-	var refreshRes struct {
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int    `json:"expires_in"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&refreshRes); err != nil {
-		log.Println("Failed to decode Spotify response:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
 
 	tokenData.AccessToken = refreshRes.AccessToken
-	tokenData.ExpiresIn = time.Now().Add(time.Duration(refreshRes.ExpiresIn) * time.Second).Unix()
+	tokenData.ExpiresAt = time.Now().Add(time.Duration(refreshRes.ExpiresIn) * time.Second).Unix()
 
-	updated, _ := json.Marshal(tokenData)
-	if err := store.Client.Set(store.Ctx, userKey, updated, 0).Err(); err != nil {
-		log.Println("Failed to update token in Redis:", err)
+	if err := h.repo.SaveUserToken(r.Context(), request.ClientID, tokenData); err != nil {
+		log.Println("Failed to update token:", err)
 		http.Error(w, "Failed to save refreshed token", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,55 @@
+// Package avatar generates identicons for players without a Spotify profile image.
+package avatar
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// URL returns the path of the identicon for the given seed, usually a player ID.
+func URL(seed string) string {
+	return "/avatars/" + url.PathEscape(seed) + ".svg"
+}
+
+// Identicon renders a symmetric 5x5 identicon for the seed as SVG. The same
+// seed always gives the same image.
+func Identicon(seed string) []byte {
+	sum := sha256.Sum256([]byte(seed))
+	color := fmt.Sprintf("hsl(%d, 55%%, 50%%)", int(sum[0])*360/256)
+
+	var cells strings.Builder
+	for y := range 5 {
+		for x := range 3 {
+			// Each cell on the left half (and the middle column) is mirrored.
+			if sum[1+y*3+x]%2 == 0 {
+				continue
+			}
+			fmt.Fprintf(&cells, `<rect x="%d" y="%d" width="1" height="1"/>`, x, y)
+			if x < 2 {
+				fmt.Fprintf(&cells, `<rect x="%d" y="%d" width="1" height="1"/>`, 4-x, y)
+			}
+		}
+	}
+
+	return fmt.Appendf(nil,
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="-0.5 -0.5 6 6" shape-rendering="crispEdges">`+
+			`<rect x="-0.5" y="-0.5" width="6" height="6" fill="#f0f0f0"/><g fill="%s">%s</g></svg>`,
+		color, cells.String())
+}
+
+// Handler serves GET /avatars/{seed}.svg.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/avatars/")
+	seed, ok := strings.CutSuffix(name, ".svg")
+	if !ok || seed == "" {
+		http.Error(w, "Avatar not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(Identicon(seed))
+}
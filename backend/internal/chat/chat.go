@@ -0,0 +1,202 @@
+// Package chat handles the lobby chat and the emoji reactions sent over the
+// room WebSocket.
+package chat
+
+import (
+	"backend/internal/model"
+	"backend/internal/moderation"
+	"backend/internal/store"
+	"backend/internal/ws"
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxMessageLength is the maximum length of a chat message in characters.
+	MaxMessageLength = 200
+	// HistoryLength is how many chat messages are kept for players who join later.
+	HistoryLength = 50
+)
+
+// Reactions are the emoji players can react with during a round.
+var Reactions = []string{"👍", "👏", "🔥", "😂", "😮", "😢", "❤️", "🎉"}
+
+// Handler handles chat and reaction messages.
+type Handler struct {
+	repo   store.Repository
+	hub    *ws.Hub
+	filter *moderation.Filter
+}
+
+// NewHandler returns a chat Handler. Chat messages are checked against the filter.
+func NewHandler(repo store.Repository, hub *ws.Hub, filter *moderation.Filter) *Handler {
+	return &Handler{repo: repo, hub: hub, filter: filter}
+}
+
+// ChatPayload is the data of a "chat" message sent by a client.
+type ChatPayload struct {
+	Text string `json:"text"`
+}
+
+// ReactionPayload is the data of a "reaction" message sent by a client.
+type ReactionPayload struct {
+	Emoji string `json:"emoji"`
+}
+
+// chatOpen reports whether the chat is open in the state: in the lobby and
+// between rounds, but not while players answer.
+func chatOpen(state model.GameState) bool {
+	switch state {
+	case model.StateLobby, model.StateGenerating, model.StateReveal, model.StateFinished:
+		return true
+	}
+	return false
+}
+
+// reject tells the sender why their message was not sent.
+func (h *Handler) reject(roomCode, playerID, reason string) {
+	h.hub.Send(roomCode, playerID, "chat-rejected", map[string]string{
+		"reason": reason,
+	})
+}
+
+// cleanText trims the message and drops control characters.
+func cleanText(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if r != '\n' && !unicode.IsGraphic(r) {
+			return -1
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(text)
+}
+
+// ChatMessage handles "chat" WebSocket messages:
+//
+//	{
+//	  "type": "chat",
+//	  "data": { "text": "good luck everyone!" }
+//	}
+//
+// Chat is open in the lobby and between rounds. Messages must be at most 200
+// characters and pass the profanity filter. Accepted messages are added to
+// the room's chat history (the last 50 messages) and broadcast:
+//
+//	{
+//	  "type": "chat",
+//	  "data": { "playerId": "p-3f9a0c12de", "displayName": "DJ Kasia", "text": "good luck everyone!", "sentAt": "..." }
+//	}
+//
+// Rejected messages are answered only to the sender with
+// {"type": "chat-rejected", "data": {"reason": "..."}}, where reason is
+// "empty", "too_long", "not_allowed" or "chat_closed". Messages from players
+// who are not in the room are ignored.
+func (h *Handler) ChatMessage(roomCode, playerID string, data json.RawMessage) {
+	var payload ChatPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Println("invalid chat message:", err)
+		return
+	}
+
+	ctx := context.Background()
+	room, err := h.repo.GetRoom(ctx, roomCode)
+	if err != nil || !slices.Contains(room.Players, playerID) {
+		return
+	}
+
+	text := cleanText(payload.Text)
+	switch {
+	case !chatOpen(room.State()):
+		h.reject(roomCode, playerID, "chat_closed")
+		return
+	case text == "":
+		h.reject(roomCode, playerID, "empty")
+		return
+	case utf8.RuneCountInString(text) > MaxMessageLength:
+		h.reject(roomCode, playerID, "too_long")
+		return
+	case !h.filter.Allowed(text):
+		h.reject(roomCode, playerID, "not_allowed")
+		return
+	}
+
+	message := model.ChatMessage{
+		PlayerID:    playerID,
+		DisplayName: room.Profile(playerID).DisplayName,
+		Text:        text,
+		SentAt:      time.Now(),
+	}
+	if err := h.repo.AppendChat(ctx, roomCode, message, HistoryLength); err != nil {
+		log.Printf("Failed to store chat message in room %s: %v", roomCode, err)
+	}
+	h.hub.Emit(roomCode, "chat", message)
+}
+
+// ReactionMessage handles "reaction" WebSocket messages sent during a round,
+// while players answer or during the reveal:
+//
+//	{
+//	  "type": "reaction",
+//	  "data": { "emoji": "🔥" }
+//	}
+//
+// The emoji must be one of Reactions. Reactions are not stored; the whole
+// room receives:
+//
+//	{
+//	  "type": "reaction",
+//	  "data": { "playerId": "p-3f9a0c12de", "displayName": "DJ Kasia", "emoji": "🔥" }
+//	}
+//
+// Other emoji, reactions outside a round and reactions from players who are
+// not in the room are ignored.
+func (h *Handler) ReactionMessage(roomCode, playerID string, data json.RawMessage) {
+	var payload ReactionPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Println("invalid reaction message:", err)
+		return
+	}
+	if !slices.Contains(Reactions, payload.Emoji) {
+		return
+	}
+
+	room, err := h.repo.GetRoom(context.Background(), roomCode)
+	if err != nil || !slices.Contains(room.Players, playerID) {
+		return
+	}
+	if state := room.State(); state != model.StateInRound && state != model.StateReveal {
+		return
+	}
+
+	h.hub.Emit(roomCode, "reaction", map[string]string{
+		"playerId":    playerID,
+		"displayName": room.Profile(playerID).DisplayName,
+		"emoji":       payload.Emoji,
+	})
+}
+
+// Presence sends the chat history to players when they connect to a room:
+//
+//	{
+//	  "type": "chat-history",
+//	  "data": [{ "playerId": "...", "displayName": "...", "text": "...", "sentAt": "..." }]
+//	}
+//
+// It is registered with ws.Hub.OnPresence.
+func (h *Handler) Presence(roomCode, playerID string, online bool) {
+	if !online {
+		return
+	}
+	messages, err := h.repo.GetChat(context.Background(), roomCode)
+	if err != nil {
+		log.Printf("Failed to load chat history of room %s: %v", roomCode, err)
+		return
+	}
+	h.hub.Send(roomCode, playerID, "chat-history", messages)
+}
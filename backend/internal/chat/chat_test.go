@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"backend/internal/model"
+	"backend/internal/moderation"
+	"backend/internal/store"
+	"backend/internal/ws"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func newTestHandler(t *testing.T) (*Handler, store.Repository) {
+	t.Helper()
+	repo := store.NewMemoryRepository()
+	hub := ws.NewHub()
+	go hub.Run()
+	room := model.Room{Code: "ABC123", HostId: "host", Players: []string{"host", "p1"}}
+	if err := repo.CreateRoom(context.Background(), room); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PROFANITY_WORDS", "")
+	return NewHandler(repo, hub, moderation.FilterFromEnv()), repo
+}
+
+func sendChat(t *testing.T, h *Handler, playerID, text string) {
+	t.Helper()
+	data, err := json.Marshal(ChatPayload{Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.ChatMessage("ABC123", playerID, data)
+}
+
+func TestChatMessageFilter(t *testing.T) {
+	h, repo := newTestHandler(t)
+
+	// Clean sentences whose words would contain a blocked word if joined.
+	sendChat(t, h, "p1", "push it to the limit")
+	sendChat(t, h, "p1", "sushi today? Hancock is buying")
+	// Blocked.
+	sendChat(t, h, "p1", "this is sh1t")
+	// Not in the room.
+	sendChat(t, h, "stranger", "hello")
+
+	history, err := repo.GetChat(context.Background(), "ABC123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, message := range history {
+		texts = append(texts, message.Text)
+	}
+	want := []string{"push it to the limit", "sushi today? Hancock is buying"}
+	if len(texts) != len(want) {
+		t.Fatalf("chat history = %q, want %q", texts, want)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Errorf("message %d = %q, want %q", i, texts[i], want[i])
+		}
+	}
+}
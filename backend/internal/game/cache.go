@@ -0,0 +1,130 @@
+package game
+
+import (
+	"backend/internal/model"
+	"backend/internal/spotify"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// sourceCacheTTL is how long the tracks of a playlist or an artist are
+	// kept. After sourceCacheFresh they are revalidated before each use.
+	sourceCacheTTL   = 24 * time.Hour
+	sourceCacheFresh = 10 * time.Minute
+
+	// maxCandidateTracks is how many tracks of a playlist or an artist are
+	// fetched at most; sourceTracks picks from them.
+	maxCandidateTracks = 200
+	// albumWorkers is how many albums are fetched at the same time.
+	albumWorkers = 4
+)
+
+// cachedTracks returns the tracks of a playlist or an artist from the cache
+// when they are still current, or fetches and caches them.
+//
+// version returns the current version of the source: the playlist's
+// snapshot ID, or the ETag of the artist's albums. It gets the cached
+// version and may answer spotify.ErrNotModified when nothing changed. A
+// source without a version is fetched again once the cache is no longer fresh.
+func (h *Handler) cachedTracks(ctx context.Context, kind, id, market string, version func(cached string) (string, error), fetch func() ([]model.Track, error)) []model.Track {
+	cached, err := h.repo.GetSourceTracks(ctx, kind, id, market)
+	hit := err == nil && len(cached.Tracks) > 0
+	if hit && time.Since(cached.FetchedAt) < sourceCacheFresh {
+		return cached.Tracks
+	}
+
+	current, err := version(cached.Version)
+	if hit && (errors.Is(err, spotify.ErrNotModified) || err == nil && current != "" && current == cached.Version) {
+		cached.FetchedAt = time.Now()
+		h.saveSourceTracks(ctx, kind, id, market, cached)
+		return cached.Tracks
+	}
+	if err != nil && !errors.Is(err, spotify.ErrNotModified) {
+		log.Printf("Failed to check the version of %s %s: %v", kind, id, err)
+		current = ""
+	}
+
+	tracks, err := fetch()
+	if err != nil {
+		log.Printf("Error fetching %s %s: %v", kind, id, err)
+		if hit {
+			return cached.Tracks
+		}
+		return tracks
+	}
+	if len(tracks) > 0 {
+		h.saveSourceTracks(ctx, kind, id, market, model.CachedTracks{Tracks: tracks, Version: current, FetchedAt: time.Now()})
+	}
+	return tracks
+}
+
+func (h *Handler) saveSourceTracks(ctx context.Context, kind, id, market string, tracks model.CachedTracks) {
+	if err := h.repo.SaveSourceTracks(ctx, kind, id, market, tracks, sourceCacheTTL); err != nil {
+		log.Printf("Failed to cache %s %s: %v", kind, id, err)
+	}
+}
+
+// fetchAlbums fetches the tracks of the albums with albumWorkers concurrent
+// requests. It stops once maxCandidateTracks tracks are collected; albums
+// that fail are skipped.
+func (h *Handler) fetchAlbums(ctx context.Context, albumIDs []string, token, market string) []model.Track {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan string)
+	results := make(chan []model.Track)
+	var wg sync.WaitGroup
+	for range min(albumWorkers, len(albumIDs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for albumID := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				var tracks []model.Track
+				for track, err := range h.spotify.AlbumTracks(ctx, token, albumID, market) {
+					if err != nil {
+						if ctx.Err() == nil {
+							log.Printf("Error fetching tracks of album %s: %v", albumID, err)
+						}
+						break
+					}
+					tracks = append(tracks, track)
+				}
+				select {
+				case results <- tracks:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, albumID := range albumIDs {
+			select {
+			case jobs <- albumID:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var allTracks []model.Track
+	for tracks := range results {
+		allTracks = append(allTracks, tracks...)
+		if len(allTracks) >= maxCandidateTracks {
+			break
+		}
+	}
+	return allTracks
+}
@@ -0,0 +1,84 @@
+package game
+
+import (
+	"backend/internal/model"
+)
+
+// Reasons a track is left out of the pool, as counted in poolRules.removed.
+const (
+	removedExplicit = "explicit"
+	removedTooShort = "tooShort"
+	removedTooLong  = "tooLong"
+	removedVersion  = "version"
+)
+
+// poolRules are the host's filters and preferences for one game's pool.
+type poolRules struct {
+	settings model.GameSettings
+	// played holds the tracks of the room's recent games, used last.
+	played map[string]bool
+	// removed counts the tracks each filter left out.
+	removed map[string]int
+}
+
+// newPoolRules returns the rules of the room's settings: "excludeExplicit",
+// "minDurationSeconds" / "maxDurationSeconds" and "excludeVersions" (live
+// versions, remixes, covers, karaoke, ...) filter the pool, and with
+// "avoidRecentGames": N the tracks of the room's last N games are used last.
+// Tracks shorter than the 15-second clip are always left out.
+func newPoolRules(room model.Room) *poolRules {
+	settings := room.Settings.WithDefaults()
+	return &poolRules{
+		settings: settings,
+		played:   room.RecentTrackIDs(settings.AvoidRecentGames),
+		removed:  make(map[string]int),
+	}
+}
+
+// apply filters the tracks and moves the recently played ones to the end.
+func (p *poolRules) apply(tracks []model.Track) []model.Track {
+	kept := make([]model.Track, 0, len(tracks))
+	for _, track := range tracks {
+		if reason := p.exclude(track); reason != "" {
+			p.removed[reason]++
+			continue
+		}
+		kept = append(kept, track)
+	}
+	return preferUnplayed(kept, p.played)
+}
+
+// exclude returns why the track is left out of the pool, or "". Tracks
+// shorter than a clip are always left out.
+func (p *poolRules) exclude(track model.Track) string {
+	switch {
+	case track.Duration <= clipMs:
+		return removedTooShort
+	case p.settings.ExcludeExplicit && track.Explicit:
+		return removedExplicit
+	case track.Duration < p.settings.MinDurationSeconds*1000:
+		return removedTooShort
+	case p.settings.MaxDurationSeconds != 0 && track.Duration > p.settings.MaxDurationSeconds*1000:
+		return removedTooLong
+	case p.settings.ExcludeVersions && model.IsAlternateVersion(track.Name):
+		return removedVersion
+	}
+	return ""
+}
+
+// preferUnplayed moves the tracks in played to the end, keeping the order otherwise.
+func preferUnplayed(tracks []model.Track, played map[string]bool) []model.Track {
+	if len(played) == 0 {
+		return tracks
+	}
+	sorted := make([]model.Track, 0, len(tracks))
+	var again []model.Track
+	for _, track := range tracks {
+		if played[track.ID] {
+			again = append(again, track)
+		} else {
+			sorted = append(sorted, track)
+		}
+	}
+	return append(sorted, again...)
+}
@@ -1,53 +1,78 @@
 package game
 
 import (
+	"backend/internal/apierror"
+	"backend/internal/history"
 	"backend/internal/model"
+	"backend/internal/spotify"
 	"backend/internal/store"
 	"backend/internal/ws"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
-	"math/rand"
 	"net/http"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 )
 
+// Handler serves the game endpoints and runs the quiz loop. Room data is read
+// and written through the repository, clients are notified through the hub and
+// finished games are saved to the history store.
+type Handler struct {
+	repo    store.Repository
+	hub     *ws.Hub
+	history history.Store
+	spotify *spotify.Client
+	jobs    *generationJobs
+}
+
+// NewHandler returns a game Handler. The history store may be nil, in which
+// case finished games are not recorded.
+func NewHandler(repo store.Repository, hub *ws.Hub, history history.Store, client *spotify.Client) *Handler {
+	return &Handler{repo: repo, hub: hub, history: history, spotify: client, jobs: newGenerationJobs()}
+}
+
 // StartGameHandler handles HTTP POST requests to /start-game.
 //
-// It expects a JSON payload in the following format:
+// It expects an "Authorization: Bearer <token>" header with the host's
+// access token and a JSON payload in the following format:
 //
 //	{
 //	  "roomCode": "ABC123",
-//	  "hostId": "spotify-user-456"
+//	  "hostId": "spotify-user-456",
+//	  "gameMode": "playlist",
+//	  "tracksData": "37i9dQZF1DXcBWIGoYBM5M",
+//	  "settings": { "questionCount": 10, "answerSeconds": 15, "revealSeconds": 5 }
 //	}
 //
-// The handler performs the following steps:
-//
-//  1. Decodes the JSON request body into a StartGameRequest struct.
-//  2. Retrieves the Room object from Redis using key "room:{roomCode}".
-//  3. Verifies that the requesting user (hostId) matches the room's HostId.
-//  4. Iterates over all players in the room and attempts to fetch their saved tracks
-//     from Redis under the key "tracks:{roomCode}:{playerId}".
-//     - Invalid or missing track data is logged and skipped.
-//  5. Combines all retrieved tracks, shuffles them, and selects the first 10 (or fewer).
-//  6. Calls GenerateQuestions with the selected tracks to create quiz questions.
-//  7. Stores the generated []Question in Redis under key "questions:{roomCode}" with a TTL of 60 minutes.
-//  8. Launches the quiz loop asynchronously via RunQuizLoop(roomCode).
-//  9. Broadcasts a "game-started" message via WebSocket to all clients in the room.
+// "gameMode" is "players", "playlist", "artist", "album" or "genre", and
+// "tracksData" the playlist or artist ID, up to 10 comma-separated album IDs
+// or a genre query such as "indie rock:1990-1999". A "mixed" game sends up to
+// 5 weighted "sources" instead, e.g. [{ "mode": "players", "weight": 2 }].
+// An empty "gameMode" reuses the game stored on the room, e.g. the one
+// prepared with /prepare-game or chosen with /play-again. "settings" is
+// optional and replaces the room's settings.
 //
-// 10. Responds with a JSON object containing:
+// The room moves from "lobby" to "generating" and the questions are taken
+// from /prepare-game when they match the game, or generated now (see
+// generate). The quiz loop starts after countdownSeconds; the room receives
+// "game-started" and a "countdown" with "startsAt" and "serverTime" in Unix
+// milliseconds. The host may start before every player is ready.
 //
 //	Response:
 //	{
 //	  "status": "started",
-//	  "questionsCount": 10
+//	  "questionsCount": 10,
+//	  "startsAt": 1760000005000,
+//	  "removedTracks": { "explicit": 4 }
 //	}
 //
-// If the host is invalid, Redis access fails, or question generation fails,
-// the handler responds with an appropriate HTTP error (e.g. 400, 403, 500).
-func StartGameHandler(w http.ResponseWriter, r *http.Request) {
+// Error codes: "invalid_query" and "invalid_settings" (400), "room_not_found"
+// (404), "invalid_state" (409) outside the lobby and "not_enough_tracks"
+// (422). A wrong hostId gets 403 and a missing token 401. If generation
+// fails, the room goes back to "lobby".
+func (h *Handler) StartGameHandler(w http.ResponseWriter, r *http.Request) {
 	var request model.StartGameRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
@@ -63,77 +88,95 @@ func StartGameHandler(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	token = strings.TrimSpace(token)
 
-	roomKey := "room:" + request.RoomCode
-	data, err := store.Client.Get(store.Ctx, roomKey).Result()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Room not found", http.StatusNotFound)
 		return
 	}
-	var room model.Room
-	err = json.Unmarshal([]byte(data), &room)
 	if err != nil {
-		http.Error(w, "Invalid room object", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	if request.HostId != room.HostId {
 		http.Error(w, "Invalid HostId", http.StatusForbidden)
 		return
 	}
-	mode := request.GameMode
-	query := request.QueryData
-
-	var allTracks []model.Track
-
-	switch mode {
-	case "players":
-		allTracks = tracksFromPlayers(room.Players, room.Code)
-	case "playlist":
-		allTracks = tracksFromPlaylist(query, token)
-	case "artist":
-		allTracks = tracksFromArtist(query, token)
-	default:
-		http.Error(w, "Unsupported game mode", http.StatusBadRequest)
+	spec, err := resolveGame(request, room)
+	if err != nil {
+		writeGameError(w, err)
 		return
 	}
+	if request.Settings != nil {
+		if err := request.Settings.Validate(); err != nil {
+			apierror.Write(w, http.StatusBadRequest, "invalid_settings", err.Error())
+			return
+		}
+	}
 
-	rand.Shuffle(len(allTracks), func(i, j int) {
-		allTracks[i], allTracks[j] = allTracks[j], allTracks[i]
+	market := h.hostMarket(r.Context(), room, token)
+	room, err = h.transition(r.Context(), request.RoomCode, model.StateGenerating, func(room *model.Room) error {
+		room.Market = market
+		room.GameMode = spec.Mode
+		room.QueryData = spec.Query
+		room.Sources = spec.Sources
+		if request.Settings != nil {
+			room.Settings = *request.Settings
+		}
+		return nil
 	})
-	var selectedTracks []model.Track
-	if len(allTracks) < 10 {
-		selectedTracks = allTracks
-	} else {
-		selectedTracks = allTracks[:10]
+	if err != nil {
+		writeStateError(w, err)
+		return
 	}
 
-	questions, err := GenerateQuestions(selectedTracks, token)
+	// Use the questions /prepare-game made for this very game, waiting for
+	// them if they are still being generated.
+	key := generationKey(room, spec)
+	h.jobs.wait(r.Context(), room.Code, key)
+	h.jobs.cancel(room.Code)
+	draft, err := h.repo.GetDraft(r.Context(), room.Code)
+	if err != nil || draft.Key != key {
+		draft, err = h.generate(r.Context(), room, spec, token, nil)
+	}
+	h.repo.DeleteDraft(r.Context(), room.Code)
+	var notEnough *notEnoughTracksError
+	if errors.As(err, &notEnough) {
+		h.rollbackToLobby(r.Context(), request.RoomCode)
+		writeNotEnoughTracks(w, notEnough.found, notEnough.needed)
+		return
+	}
 	if err != nil {
+		h.rollbackToLobby(r.Context(), request.RoomCode)
 		http.Error(w, "Failed to generate questions", http.StatusInternalServerError)
 		return
 	}
+	questions := draft.Questions
 
-	jsonData, _ := json.Marshal(questions)
-	err = store.Client.Set(store.Ctx, "questions:"+request.RoomCode, jsonData, 60*time.Minute).Err()
+	err = h.repo.SaveQuestions(r.Context(), request.RoomCode, questions)
 	if err != nil {
+		h.rollbackToLobby(r.Context(), request.RoomCode)
 		http.Error(w, "Failed to save questions", http.StatusInternalServerError)
 		return
 	}
 
-	go RunQuizLoop(request.RoomCode)
-	message := map[string]any{
-		"type": "game-started",
-	}
+	settings := room.Settings.WithDefaults()
+	countdown := time.Duration(settings.CountdownSeconds) * time.Second
+	now := time.Now()
+	startsAt := now.Add(countdown)
 
-	payload, _ := json.Marshal(message)
-	ws.GlobalHub.Broadcast <- ws.BroadcastMessage{
-		RoomCode: request.RoomCode,
-		Data:     payload,
-	}
+	go h.RunQuizLoop(request.RoomCode, startsAt)
+	h.hub.Emit(request.RoomCode, "game-started", nil)
+	h.hub.Emit(request.RoomCode, "countdown", map[string]any{
+		"startsAt":   startsAt.UnixMilli(),
+		"serverTime": now.UnixMilli(),
+		"seconds":    int(countdown.Seconds()),
+	})
 	json.NewEncoder(w).Encode(map[string]any{
 		"status":         "started",
 		"questionsCount": len(questions),
+		"startsAt":       startsAt.UnixMilli(),
+		"removedTracks":  draft.RemovedTracks,
 	})
-
 }
 
 // GetQuestionsHandler handles HTTP GET requests to /room/{code}/questions.
@@ -161,21 +204,14 @@ func StartGameHandler(w http.ResponseWriter, r *http.Request) {
 //     ]
 //
 // If the room or questions cannot be found, responds with HTTP 500.
-func GetQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetQuestionsHandler(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	parts := strings.Split(path, "/")
-	key := "questions:" + parts[2]
-	raw, err := store.Client.Get(store.Ctx, key).Result()
+	questions, err := h.repo.GetQuestions(r.Context(), parts[2])
 	if err != nil {
 		http.Error(w, "Failed to get questions", http.StatusInternalServerError)
 		return
 	}
-	var questions []model.Question
-	err = json.Unmarshal([]byte(raw), &questions)
-	if err != nil {
-		http.Error(w, "Failed to parse questions", http.StatusInternalServerError)
-		return
-	}
 	json.NewEncoder(w).Encode(questions)
 }
 
@@ -194,11 +230,15 @@ func GetQuestionsHandler(w http.ResponseWriter, r *http.Request) {
 //
 //  1. Parses and validates the incoming JSON payload as AnswerRequest.
 //
-//  2. Retrieves the list of questions for the given room from Redis under key:
+//  2. Verifies that the room is in the "in-round" state, otherwise responds with
+//     409 and the "invalid_state" code. Players that are not in the room, e.g.
+//     kicked or banned ones, get 404 and the "player_not_found" code.
+//     Retrieves the list of questions for the given room from Redis under key:
 //     "questions:{roomCode}".
 //
-//  3. Searches for the specific question matching the given questionId.
-//     If not found, responds with 404 Not Found.
+//  3. Checks that questionId is the question of the running round (the room's
+//     CurrentQIdx). Answers to revealed or not yet sent questions get 409 and
+//     the "invalid_state" code.
 //
 //  4. Retrieves the player's current score from Redis under key:
 //     "score:{roomCode}:{playerId}". If not present or invalid, defaults to 0.
@@ -211,11 +251,15 @@ func GetQuestionsHandler(w http.ResponseWriter, r *http.Request) {
 //     - 1 point is subtracted every 20 milliseconds.
 //     - The minimum awarded points is 500.
 //
-//  7. If the selected answer is correct:
+//  7. Records the answer (selected option, correctness, response time and points)
+//     in the Redis hash "answers:{roomCode}:{questionId}" for the post-game summary.
+//     Only the first answer of a player counts; repeated answers get 409 Conflict.
+//
+//  8. If the selected answer is correct:
 //     - Adds the calculated points to the player's score.
 //     - Saves the new score back to Redis with a 60-minute TTL.
 //
-//  8. Responds with a JSON payload indicating if the answer was correct,
+//  9. Responds with a JSON payload indicating if the answer was correct,
 //     the player's updated total score, and the number of points earned:
 //
 //     Example Response:
@@ -225,9 +269,9 @@ func GetQuestionsHandler(w http.ResponseWriter, r *http.Request) {
 //     "earned": 840
 //     }
 //
-// In case of any decoding errors, missing question or Redis failures, responds with appropriate
-// HTTP error codes (400, 404, or 500).
-func SubmitAnswerHandler(w http.ResponseWriter, r *http.Request) {
+// In case of any decoding errors, a missing room or player or Redis failures, responds with appropriate
+// HTTP error codes (400, 404, 409 or 500).
+func (h *Handler) SubmitAnswerHandler(w http.ResponseWriter, r *http.Request) {
 	var request model.AnswerRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
@@ -235,69 +279,84 @@ func SubmitAnswerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	key := "questions:" + request.RoomCode
-	data, err := store.Client.Get(store.Ctx, key).Result()
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
 	if err != nil {
-		http.Error(w, "Failed to get questions", http.StatusInternalServerError)
+		writeStateError(w, err)
 		return
 	}
-
-	var questions []model.Question
-	err = json.Unmarshal([]byte(data), &questions)
-	if err != nil {
-		http.Error(w, "Invalid questions data", http.StatusInternalServerError)
+	if room.State() != model.StateInRound {
+		apierror.Write(w, http.StatusConflict, "invalid_state", "Answers are only accepted while a round is running")
 		return
 	}
-	var question model.Question
-	found := false
-	for _, q := range questions {
-		if q.ID == request.QuestionID {
-			question = q
-			found = true
-			break
-		}
-	}
-	if !found {
-		http.Error(w, "Question not found", http.StatusNotFound)
+	// Kicked and banned players are no longer in Players; the ban list is
+	// checked as well in case a banned ID is still listed.
+	if !slices.Contains(room.Players, request.PlayerID) || slices.Contains(room.Banned, strings.ToLower(request.PlayerID)) {
+		apierror.Write(w, http.StatusNotFound, "player_not_found", "Player is not in the room")
 		return
 	}
-	scoreKey := fmt.Sprintf("score:%s:%s", request.RoomCode, request.PlayerID)
-	currentScore := 0
 
-	rawScore, err := store.Client.Get(store.Ctx, scoreKey).Result()
-	if err == nil {
-		currentScore, err = strconv.Atoi(rawScore)
-		if err != nil {
-			log.Println("Invalid score in Redis, resetting to 0")
-			currentScore = 0
-		}
+	questions, err := h.repo.GetQuestions(r.Context(), request.RoomCode)
+	if err != nil {
+		http.Error(w, "Failed to get questions", http.StatusInternalServerError)
+		return
+	}
+	current := room.CurrentQIdx - 1
+	if current < 0 || current >= len(questions) || questions[current].ID != request.QuestionID {
+		apierror.Write(w, http.StatusConflict, "invalid_state", "Only the current question can be answered")
+		return
 	}
-	timestampKey := fmt.Sprintf("question-time:%s:%s", request.RoomCode, request.QuestionID)
-	sentStr, err := store.Client.Get(store.Ctx, timestampKey).Result()
+	question := questions[current]
+	currentScore, err := h.repo.GetScore(r.Context(), request.RoomCode, request.PlayerID)
 	if err != nil {
-		log.Println("Failed to fetch question time:", err)
+		log.Println("Invalid score, resetting to 0:", err)
+		currentScore = 0
 	}
 
-	sentAt, err := strconv.ParseInt(sentStr, 10, 64)
-	var points int
+	var sentAt int64
+	sentTime, err := h.repo.GetQuestionTime(r.Context(), request.RoomCode, request.QuestionID)
 	if err != nil {
-		log.Println("Failed to parse timestamp:", err)
-		points = 500
+		log.Println("Failed to fetch question time:", err)
+	} else {
+		sentAt = sentTime.UnixMilli()
 	}
+
 	now := time.Now().UnixMilli()
 	diff := (now - sentAt) / 20
-	points = 1000 - int(diff*1)
+	points := 1000 - int(diff*1)
 	points = max(points, 500)
 
-	if request.Selected == question.CorrectAnswer {
-		currentScore += points
-		store.Client.Set(store.Ctx, scoreKey, currentScore, 60*time.Minute)
+	correct := request.Selected == question.CorrectAnswer
+	answer := model.AnswerRecord{
+		PlayerID: request.PlayerID,
+		Selected: request.Selected,
+		Correct:  correct,
+		Points:   points,
+	}
+	if sentAt > 0 {
+		answer.ResponseMs = now - sentAt
+	}
+	if !correct {
+		answer.Points = 0
+	}
+	recorded, err := h.repo.RecordAnswer(r.Context(), request.RoomCode, request.QuestionID, answer)
+	if err != nil {
+		http.Error(w, "Failed to save answer", http.StatusInternalServerError)
+		return
+	}
+	if !recorded {
+		http.Error(w, "Answer already submitted", http.StatusConflict)
+		return
+	}
+	if correct {
+		updated, err := h.repo.AddScore(r.Context(), request.RoomCode, request.PlayerID, points)
 		if err != nil {
 			log.Println("Failed to update score:", err)
+		} else {
+			currentScore = updated
 		}
 	}
 	json.NewEncoder(w).Encode(map[string]any{
-		"correct": request.Selected == question.CorrectAnswer,
+		"correct": correct,
 		"score":   currentScore,
 		"earned":  points,
 	})
@@ -321,194 +380,39 @@ func SubmitAnswerHandler(w http.ResponseWriter, r *http.Request) {
 //     under the key "score:{roomCode}:{playerId}". If no score is found or parsing fails,
 //     the player is assumed to have a score of 0.
 //
-//  5. Builds a scoreboard as a map of player IDs to scores.
+//  5. Builds the scoreboard with each player's ID, display name and avatar,
+//     highest score first.
 //
 //  6. Responds with the full scoreboard as a JSON object:
 //
 //     Response:
 //     {
-//     "scoreboard": {
-//     "spotify-user-1": 2000,
-//     "spotify-user-2": 1000,
-//     "guest123": 0
-//     }
+//     "scoreboard": [
+//     { "playerId": "p-3f9a0c12de", "displayName": "DJ Kasia", "avatar": "...", "score": 2000 },
+//     { "playerId": "guest123", "displayName": "guest123", "avatar": "...", "score": 0 }
+//     ]
 //     }
 //
 // In case of an error (e.g. room not found or Redis failure),
 // responds with the appropriate HTTP error status.
-func GetScoreboardHandler(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-
-	var room model.Room
-	roomCode := parts[2]
-	data, err := store.Client.Get(store.Ctx, "room:"+roomCode).Result()
-	if err != nil {
-		http.Error(w, "Room not found", http.StatusNotFound)
-		return
-	}
-
-	err = json.Unmarshal([]byte(data), &room)
-	if err != nil {
-		http.Error(w, "Failed to parse room", http.StatusInternalServerError)
-		return
-	}
-
-	scoreboard := make(map[string]int)
-	for _, player := range room.Players {
-
-		scoreKey := fmt.Sprintf("score:%s:%s", room.Code, player)
-		scoreData, err := store.Client.Get(store.Ctx, scoreKey).Result()
-		if err != nil {
-			log.Printf("Failed to fetch or parse score for player %s: %v", player, err)
-
-		}
-
-		score := 0
-		if err == nil {
-
-			score, err = strconv.Atoi(scoreData)
-			if err != nil {
-
-				log.Println("Failed to update score:", err)
-			}
-		}
-
-		scoreboard[player] = score
-	}
-	json.NewEncoder(w).Encode(map[string]any{
-		"scoreboard": scoreboard,
-	})
-
-}
-
-// GetNextQuestionHandler handles HTTP GET requests to /room/{code}/next-question.
-//
-// It expects the room code to be embedded in the URL path, for example:
-//
-//	GET /room/ABC123/next-question
-//
-// The handler performs the following steps:
-//
-//  1. Parses the room code from the URL.
-//
-//  2. Retrieves the Room object from Redis (key: "room:{roomCode}").
-//
-//  3. Checks the CurrentQIdx field of the room to determine which question is next.
-//
-//  4. Retrieves the full list of questions from Redis (key: "questions:{roomCode}").
-//
-//  5. If there are no more questions (i.e. CurrentQIdx >= len(questions)),
-//     responds with HTTP 204 No Content to indicate the end of the quiz.
-//
-//  6. Otherwise:
-//     - Increments the CurrentQIdx by 1,
-//     - Updates the Room in Redis,
-//     - Returns the next question and its index.
-//
-//     Example response:
-//
-//     {
-//     "question": { ... },
-//     "index": 2,
-//     "total": 10,
-//     }
-//
-// In case of errors (e.g. invalid room code, Redis error, parse failure),
-// responds with the appropriate HTTP error status.
-func GetNextQuestionHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetScoreboardHandler(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	parts := strings.Split(path, "/")
 
 	roomCode := parts[2]
-
-	var room model.Room
-	data, err := store.Client.Get(store.Ctx, "room:"+roomCode).Result()
-	if err != nil {
+	room, err := h.repo.GetRoom(r.Context(), roomCode)
+	if errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "Room not found", http.StatusNotFound)
 		return
 	}
-	err = json.Unmarshal([]byte(data), &room)
-	if err != nil {
-		http.Error(w, "Failed to parse room", http.StatusInternalServerError)
-		return
-	}
-
-	currentQuestionIdx := room.CurrentQIdx
-	room.CurrentQIdx++
-	updatedRoomData, _ := json.Marshal(room)
-	store.Client.Set(store.Ctx, "room:"+roomCode, updatedRoomData, 60*time.Minute)
-
-	var questions []model.Question
-	data, err = store.Client.Get(store.Ctx, "questions:"+roomCode).Result()
-	if err != nil {
-		http.Error(w, "Questions not found", http.StatusNotFound)
-		return
-	}
-
-	err = json.Unmarshal([]byte(data), &questions)
 	if err != nil {
-		http.Error(w, "Failed to parse questions", http.StatusInternalServerError)
-		return
-	}
-	if currentQuestionIdx >= len(questions) {
-		scoreboard := make(map[string]int)
-		for _, player := range room.Players {
-			data, err := store.Client.Get(store.Ctx, "score:"+roomCode+":"+player).Result()
-			if err != nil {
-				log.Printf("Failed to fetch or parse score for player %s: %v", player, err)
-			}
-
-			score := 0
-			if err == nil {
-
-				score, err = strconv.Atoi(data)
-				if err != nil {
-
-					log.Println("Failed to update score:", err)
-				}
-			}
-
-			scoreboard[player] = score
-		}
-
-		message := map[string]any{
-			"type": "game-over",
-			"data": scoreboard,
-		}
-		payload, _ := json.Marshal(message)
-		ws.GlobalHub.Broadcast <- ws.BroadcastMessage{
-			RoomCode: roomCode,
-			Data:     payload,
-		}
-
-		store.Client.Del(store.Ctx, "room:"+roomCode)
-		store.Client.Del(store.Ctx, "questions:"+roomCode)
-		for _, player := range room.Players {
-			store.Client.Del(store.Ctx, "score:"+roomCode+":"+player)
-			store.Client.Del(store.Ctx, "tracks:"+roomCode+":"+player)
-
-		}
+		http.Error(w, "Failed to load room", http.StatusInternalServerError)
 		return
-
-	}
-
-	currentQuestion := questions[currentQuestionIdx]
-	message := map[string]any{
-		"type": "question",
-		"data": currentQuestion,
-	}
-	payload, _ := json.Marshal(message)
-
-	ws.GlobalHub.Broadcast <- ws.BroadcastMessage{
-		RoomCode: roomCode,
-		Data:     payload,
 	}
 
+	scoreboard := h.collectScoreboard(r.Context(), room.Code, room.Players)
 	json.NewEncoder(w).Encode(map[string]any{
-		"question": currentQuestion,
-		"index":    currentQuestionIdx,
-		"total":    len(questions),
+		"scoreboard": rankScoreboard(room, scoreboard),
 	})
 
 }
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"backend/internal/model"
+	"backend/internal/store"
+	"backend/internal/ws"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHandler(t *testing.T, room model.Room) (*Handler, *store.MemoryRepository) {
+	t.Helper()
+	repo := store.NewMemoryRepository()
+	if err := repo.CreateRoom(context.Background(), room); err != nil {
+		t.Fatal(err)
+	}
+	hub := ws.NewHub()
+	go hub.Run()
+	return NewHandler(repo, hub, nil, nil), repo
+}
+
+func submitAnswer(h *Handler, request model.AnswerRequest) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(request)
+	rec := httptest.NewRecorder()
+	h.SubmitAnswerHandler(rec, httptest.NewRequest(http.MethodPost, "/submit-answer", strings.NewReader(string(data))))
+	return rec
+}
+
+func TestSubmitAnswerScoring(t *testing.T) {
+	ctx := context.Background()
+	h, repo := newTestHandler(t, model.Room{
+		Code:        "ABC123",
+		HostId:      "host",
+		Players:     []string{"host", "p1", "p2"},
+		Banned:      []string{"troll"},
+		GameState:   model.StateInRound,
+		CurrentQIdx: 1,
+	})
+	repo.SaveQuestions(ctx, "ABC123", []model.Question{{ID: "q1", CorrectAnswer: "Right"}, {ID: "q2", CorrectAnswer: "Right"}})
+	repo.SetQuestionTime(ctx, "ABC123", "q1", time.Now())
+
+	var result struct {
+		Correct bool `json:"correct"`
+		Score   int  `json:"score"`
+		Earned  int  `json:"earned"`
+	}
+	rec := submitAnswer(h, model.AnswerRequest{RoomCode: "ABC123", QuestionID: "q1", Selected: "Right", PlayerID: "p1"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("answer: %d %s", rec.Code, rec.Body)
+	}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	// An immediate answer earns close to 1000 points, never less than 500.
+	if !result.Correct || result.Earned < 900 || result.Earned > 1000 || result.Score != result.Earned {
+		t.Fatalf("correct answer = %+v", result)
+	}
+	if score, _ := repo.GetScore(ctx, "ABC123", "p1"); score != result.Earned {
+		t.Fatalf("stored score = %d, want %d", score, result.Earned)
+	}
+
+	// Only the first answer counts.
+	if rec := submitAnswer(h, model.AnswerRequest{RoomCode: "ABC123", QuestionID: "q1", Selected: "Right", PlayerID: "p1"}); rec.Code != http.StatusConflict {
+		t.Fatalf("second answer: %d, want 409", rec.Code)
+	}
+
+	// Wrong answers score nothing.
+	rec = submitAnswer(h, model.AnswerRequest{RoomCode: "ABC123", QuestionID: "q1", Selected: "Wrong", PlayerID: "p2"})
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result.Correct || result.Score != 0 {
+		t.Fatalf("wrong answer = %+v", result)
+	}
+
+	// Players not in the room cannot answer.
+	for _, playerID := range []string{"stranger", "troll"} {
+		rec := submitAnswer(h, model.AnswerRequest{RoomCode: "ABC123", QuestionID: "q1", Selected: "Right", PlayerID: playerID})
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "player_not_found") {
+			t.Errorf("answer of %s: %d %s, want 404 player_not_found", playerID, rec.Code, rec.Body)
+		}
+	}
+	answers, _ := repo.GetAnswers(ctx, "ABC123", "q1")
+	if len(answers) != 2 {
+		t.Fatalf("recorded %d answers, want 2", len(answers))
+	}
+
+	// The next question cannot be answered before it is sent.
+	rec = submitAnswer(h, model.AnswerRequest{RoomCode: "ABC123", QuestionID: "q2", Selected: "Right", PlayerID: "p2"})
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "invalid_state") {
+		t.Fatalf("answer to the next question: %d %s", rec.Code, rec.Body)
+	}
+	if answers, _ := repo.GetAnswers(ctx, "ABC123", "q2"); len(answers) != 0 {
+		t.Fatalf("recorded answers to the next question: %+v", answers)
+	}
+}
+
+func TestSubmitAnswerOutsideRound(t *testing.T) {
+	h, repo := newTestHandler(t, model.Room{Code: "ABC123", Players: []string{"p1"}, GameState: model.StateReveal})
+	repo.SaveQuestions(context.Background(), "ABC123", []model.Question{{ID: "q1", CorrectAnswer: "Right"}})
+	rec := submitAnswer(h, model.AnswerRequest{RoomCode: "ABC123", QuestionID: "q1", Selected: "Right", PlayerID: "p1"})
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "invalid_state") {
+		t.Fatalf("answer during reveal: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTransition(t *testing.T) {
+	ctx := context.Background()
+	h, repo := newTestHandler(t, model.Room{Code: "ABC123"})
+
+	room, err := h.transition(ctx, "ABC123", model.StateGenerating, func(room *model.Room) error {
+		room.GameMode = "playlist"
+		return nil
+	})
+	if err != nil || room.State() != model.StateGenerating || room.GameMode != "playlist" {
+		t.Fatalf("transition to generating: %+v, %v", room, err)
+	}
+
+	// A second start is refused and changes nothing.
+	_, err = h.transition(ctx, "ABC123", model.StateGenerating, func(room *model.Room) error {
+		room.GameMode = "artist"
+		return nil
+	})
+	var transitionErr *model.TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("second start: err = %v, want a TransitionError", err)
+	}
+	rec := httptest.NewRecorder()
+	writeStateError(rec, err)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "invalid_state") {
+		t.Fatalf("writeStateError: %d %s", rec.Code, rec.Body)
+	}
+
+	// A failed start goes back to the lobby even if the request is gone.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	h.rollbackToLobby(cancelled, "ABC123")
+	if room, _ := repo.GetRoom(ctx, "ABC123"); room.State() != model.StateLobby || room.GameMode != "playlist" {
+		t.Fatalf("room after rollback = %+v", room)
+	}
+}
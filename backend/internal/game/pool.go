@@ -0,0 +1,163 @@
+package game
+
+import (
+	"backend/internal/model"
+	"context"
+	"log"
+	"math/rand"
+	"strings"
+)
+
+// sourceTracks fetches the tracks of one game mode and its "tracksData" that
+// are available in the room's market.
+// "players" games use the songs submitted in the lobby or, without any, the
+// players' saved tracks (see refreshPlayerTracks), taking one track of each
+// player in turn. The other modes use the tracks of the playlist, of the
+// artist's albums, of the chosen albums or of a genre search; playlists and
+// artists are cached (see cachedTracks).
+// The rules filter the tracks and put recently played ones last, so they are
+// only used when nothing else is left. They run on every fetched track before
+// maxSourceTracks random tracks are picked, so a filter that removes most of
+// a playlist still leaves the rest to pick from.
+func (h *Handler) sourceTracks(ctx context.Context, room model.Room, mode, query, token string, rules *poolRules) []model.Track {
+	var tracks []model.Track
+	switch mode {
+	case "players":
+		lists := submissionsByPlayer(room)
+		if len(lists) == 0 {
+			h.refreshPlayerTracks(ctx, room.Players, room.Code, room.Settings.WithDefaults(), room.Market)
+			lists = h.tracksFromPlayers(ctx, room.Players, room.Code)
+		}
+		for i := range lists {
+			rand.Shuffle(len(lists[i]), func(a, b int) {
+				lists[i][a], lists[i][b] = lists[i][b], lists[i][a]
+			})
+			lists[i] = rules.apply(lists[i])
+		}
+		return roundRobin(lists)
+	case "playlist":
+		tracks = h.tracksFromPlaylist(ctx, query, token, room.Market)
+	case "artist":
+		tracks = h.tracksFromArtist(ctx, query, token, room.Market)
+	case "album":
+		tracks = h.tracksFromAlbums(ctx, model.AlbumIDs(query), token, room.Market)
+	case "genre":
+		genre, _ := model.ParseGenreQuery(query)
+		tracks = h.tracksFromGenre(ctx, genre, token, room.Market)
+	}
+	tracks = rules.apply(tracks)
+	return tracks[:min(len(tracks), maxSourceTracks)]
+}
+
+// playableTracks returns the first count tracks that can be played in the
+// market, checking them 50 at a time. If a check fails, the remaining tracks
+// are used unchecked rather than cancelling the game.
+func (h *Handler) playableTracks(ctx context.Context, tracks []model.Track, count int, token, market string) []model.Track {
+	var playable []model.Track
+	for start := 0; start < len(tracks) && len(playable) < count; start += 50 {
+		checked, err := h.spotify.PlayableTracks(ctx, token, tracks[start:min(start+50, len(tracks))], market)
+		if err != nil {
+			log.Println("Failed to check playable tracks:", err)
+			playable = append(playable, tracks[start:]...)
+			break
+		}
+		playable = append(playable, checked...)
+	}
+	return playable[:min(count, len(playable))]
+}
+
+// roundRobin interleaves the players' lists, taking one track of each player
+// in turn, so a player with 3 tracks is heard as often as one with 25 until
+// their tracks run out. Tracks that several players share are kept once.
+func roundRobin(lists [][]model.Track) []model.Track {
+	seen := make(trackSet)
+	var pool []model.Track
+	for i := 0; ; i++ {
+		more := false
+		for _, list := range lists {
+			if i >= len(list) {
+				continue
+			}
+			more = true
+			if seen.add(list[i]) {
+				pool = append(pool, list[i])
+			}
+		}
+		if !more {
+			return pool
+		}
+	}
+}
+
+// trackSet remembers tracks by ID and by normalized title.
+type trackSet map[string]bool
+
+// add adds the track and reports whether it was new.
+func (s trackSet) add(track model.Track) bool {
+	title := normalizeTitle(track.Name)
+	if s["id:"+track.ID] || s["title:"+title] {
+		return false
+	}
+	s["id:"+track.ID] = true
+	s["title:"+title] = true
+	return true
+}
+
+// tracksFromSources builds the pool of a "mixed" game. Every source gets a
+// quota of the pool proportional to its weight, so one large playlist cannot
+// crowd out the others; the pool is then topped up from whatever is left.
+// Tracks found in more than one source are kept once.
+func (h *Handler) tracksFromSources(ctx context.Context, room model.Room, sources []model.PoolSource, size int, token string, rules *poolRules) []model.Track {
+	lists := make([][]model.Track, len(sources))
+	weights := make([]int, len(sources))
+	for i, source := range sources {
+		lists[i] = h.sourceTracks(ctx, room, source.Mode, source.ID, token, rules)
+		weights[i] = source.EffectiveWeight()
+	}
+	return mergeTracks(lists, weights, size)
+}
+
+// mergeTracks takes up to size tracks from the lists, first up to each list's
+// weighted quota, then from the rest of the lists in order. Duplicates by
+// track ID or normalized title are skipped.
+func mergeTracks(lists [][]model.Track, weights []int, size int) []model.Track {
+	total := 0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	seen := make(trackSet)
+	used := make([]int, len(lists))
+	var pool []model.Track
+	take := func(i, limit int) {
+		for used[i] < len(lists[i]) && len(pool) < size && limit > 0 {
+			track := lists[i][used[i]]
+			used[i]++
+			if seen.add(track) {
+				pool = append(pool, track)
+				limit--
+			}
+		}
+	}
+
+	for i := range lists {
+		take(i, max(size*weights[i]/total, 1))
+	}
+	for i := range lists {
+		take(i, size)
+	}
+	return pool
+}
+
+// normalizeTitle reduces a track title to what players would recognize, so
+// "Song (Remastered 2011)" and "Song - Live" count as the same song as "Song".
+func normalizeTitle(title string) string {
+	title = strings.ToLower(title)
+	if i := strings.IndexAny(title, "(["); i > 0 {
+		title = title[:i]
+	}
+	if i := strings.Index(title, " - "); i > 0 {
+		title = title[:i]
+	}
+	return strings.Join(strings.Fields(title), " ")
+}
@@ -0,0 +1,135 @@
+package game
+
+import (
+	"backend/internal/model"
+	"slices"
+	"testing"
+)
+
+// tracks returns tracks with the given IDs, named after them.
+func tracks(ids ...string) []model.Track {
+	var list []model.Track
+	for _, id := range ids {
+		list = append(list, model.Track{ID: id, Name: "Song " + id, Duration: 200000})
+	}
+	return list
+}
+
+func ids(tracks []model.Track) []string {
+	var list []string
+	for _, track := range tracks {
+		list = append(list, track.ID)
+	}
+	return list
+}
+
+func TestMergeTracks(t *testing.T) {
+	tests := []struct {
+		name    string
+		lists   [][]model.Track
+		weights []int
+		size    int
+		want    []string
+	}{
+		{
+			name:    "weighted quotas",
+			lists:   [][]model.Track{tracks("a1", "a2", "a3", "a4"), tracks("b1", "b2", "b3", "b4")},
+			weights: []int{3, 1},
+			size:    4,
+			want:    []string{"a1", "a2", "a3", "b1"},
+		},
+		{
+			name:    "topped up when a source runs short",
+			lists:   [][]model.Track{tracks("a1"), tracks("b1", "b2", "b3", "b4")},
+			weights: []int{1, 1},
+			size:    4,
+			want:    []string{"a1", "b1", "b2", "b3"},
+		},
+		{
+			name:    "every source gets at least one track",
+			lists:   [][]model.Track{tracks("a1", "a2", "a3"), tracks("b1")},
+			weights: []int{10, 1},
+			size:    3,
+			want:    []string{"a1", "a2", "b1"},
+		},
+		{
+			name:    "duplicates across sources are kept once",
+			lists:   [][]model.Track{tracks("x", "a1"), tracks("x", "b1")},
+			weights: []int{1, 1},
+			size:    4,
+			want:    []string{"x", "a1", "b1"},
+		},
+	}
+	for _, tt := range tests {
+		if got := ids(mergeTracks(tt.lists, tt.weights, tt.size)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMergeTracksSameTitle(t *testing.T) {
+	original := model.Track{ID: "1", Name: "Song"}
+	remaster := model.Track{ID: "2", Name: "Song (Remastered 2011)"}
+	live := model.Track{ID: "3", Name: "Song - Live"}
+	other := model.Track{ID: "4", Name: "Other Song"}
+	got := mergeTracks([][]model.Track{{original, remaster}, {live, other}}, []int{1, 1}, 10)
+	if !slices.Equal(ids(got), []string{"1", "4"}) {
+		t.Fatalf("got %v, want one version of the song", ids(got))
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	lists := [][]model.Track{tracks("a1", "a2", "a3", "a4"), tracks("b1"), tracks("c1", "a2", "c2")}
+	want := []string{"a1", "b1", "c1", "a2", "a3", "c2", "a4"}
+	if got := ids(roundRobin(lists)); !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPreferUnplayed(t *testing.T) {
+	room := model.Room{PlayedTracks: [][]string{{"a"}, {"b"}, {"c"}}}
+	got := ids(preferUnplayed(tracks("a", "b", "c", "d"), room.RecentTrackIDs(2)))
+	if want := []string{"a", "d", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPoolRules(t *testing.T) {
+	room := model.Room{
+		Settings: model.GameSettings{
+			ExcludeExplicit:    true,
+			MaxDurationSeconds: 300,
+			ExcludeVersions:    true,
+			AvoidRecentGames:   1,
+		},
+		PlayedTracks: [][]string{{"played"}},
+	}
+	pool := []model.Track{
+		{ID: "played", Name: "Played", Duration: 200000},
+		{ID: "clean", Name: "Clean", Duration: 200000},
+		{ID: "explicit", Name: "Explicit", Duration: 200000, Explicit: true},
+		{ID: "short", Name: "Short", Duration: 10000},
+		{ID: "long", Name: "Long", Duration: 400000},
+		{ID: "live", Name: "Song - Live", Duration: 200000},
+		{ID: "forever", Name: "Live Forever", Duration: 200000},
+	}
+	rules := newPoolRules(room)
+	got := ids(rules.apply(pool))
+	if want := []string{"clean", "forever", "played"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	want := map[string]int{removedExplicit: 1, removedTooShort: 1, removedTooLong: 1, removedVersion: 1}
+	for reason, count := range want {
+		if rules.removed[reason] != count {
+			t.Errorf("removed[%s] = %d, want %d", reason, rules.removed[reason], count)
+		}
+	}
+}
+
+func TestCandidateTracks(t *testing.T) {
+	for _, count := range []int{1, 5, 10, 30} {
+		if spares := candidateTracks(count) - count; spares < 2 {
+			t.Errorf("candidateTracks(%d) leaves %d spares", count, spares)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package game
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// generationTimeout bounds a background generation.
+const generationTimeout = 3 * time.Minute
+
+// gameSpec is the game mode and the track sources of a game.
+type gameSpec struct {
+	Mode    string             `json:"mode"`
+	Query   string             `json:"query,omitempty"`
+	Sources []model.PoolSource `json:"sources,omitempty"`
+}
+
+var errUnsupportedMode = errors.New("unsupported game mode")
+
+// resolveGame picks the game of a /start-game or /prepare-game request: its
+// sources, else its mode and query, else the ones stored on the room.
+func resolveGame(request model.StartGameRequest, room model.Room) (gameSpec, error) {
+	spec := gameSpec{Mode: request.GameMode, Query: request.QueryData, Sources: request.Sources}
+	switch {
+	case len(spec.Sources) > 0:
+		spec.Mode, spec.Query = "mixed", ""
+	case spec.Mode == "":
+		spec = gameSpec{Mode: room.GameMode, Query: room.QueryData, Sources: room.Sources}
+	}
+
+	if spec.Mode == "mixed" {
+		return spec, model.ValidateSources(spec.Sources)
+	}
+	spec.Sources = nil
+	if !model.ValidGameMode(spec.Mode) {
+		return spec, errUnsupportedMode
+	}
+	return spec, model.ValidateQuery(spec.Mode, spec.Query)
+}
+
+// writeGameError responds to an error of resolveGame.
+func writeGameError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedMode) {
+		http.Error(w, "Unsupported game mode", http.StatusBadRequest)
+		return
+	}
+	apierror.Write(w, http.StatusBadRequest, "invalid_query", err.Error())
+}
+
+// usesPlayers reports whether the game takes tracks from the players.
+func (s gameSpec) usesPlayers() bool {
+	return s.Mode == "players" || slices.ContainsFunc(s.Sources, func(source model.PoolSource) bool {
+		return source.Mode == "players"
+	})
+}
+
+// generationKey identifies everything the questions of a game depend on: the
+// game, the settings that shape the pool, the market, the games played (for
+// avoidRecentGames) and, if the players' tracks are used, the players and
+// their submitted songs.
+func generationKey(room model.Room, spec gameSpec) string {
+	settings := room.Settings.WithDefaults()
+	settings.AnswerSeconds, settings.RevealSeconds, settings.CountdownSeconds = 0, 0, 0
+	input := struct {
+		Game        gameSpec           `json:"game"`
+		Settings    model.GameSettings `json:"settings"`
+		Market      string             `json:"market"`
+		GamesPlayed int                `json:"gamesPlayed"`
+		Players     []string           `json:"players,omitempty"`
+		Submissions []string           `json:"submissions,omitempty"`
+	}{Game: spec, Settings: settings, Market: room.Market, GamesPlayed: room.GamesPlayed}
+	if spec.usesPlayers() {
+		input.Players = slices.Sorted(slices.Values(room.Players))
+		for _, track := range room.Submissions {
+			input.Submissions = append(input.Submissions, track.SubmittedBy+":"+track.ID)
+		}
+	}
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12])
+}
+
+// notEnoughTracksError is returned by generate when the pool is too small.
+type notEnoughTracksError struct {
+	found, needed int
+}
+
+func (e *notEnoughTracksError) Error() string {
+	return fmt.Sprintf("found %d usable tracks, at least %d are needed", e.found, e.needed)
+}
+
+// hostMarket returns the market of the token's user, falling back to the
+// room's market.
+func (h *Handler) hostMarket(ctx context.Context, room model.Room, token string) string {
+	profile, err := h.spotify.Me(ctx, token)
+	if err != nil {
+		log.Println("Failed to fetch host market:", err)
+		return room.Market
+	}
+	if profile.Country == "" {
+		return room.Market
+	}
+	return profile.Country
+}
+
+// generate builds the track pool of the game and turns it into questions.
+//
+// The pool comes from sourceTracks, or from tracksFromSources for "mixed"
+// games, filtered by the room's poolRules. The host receives how many tracks
+// each filter removed as a "pool-filtered" message, e.g.
+// { "explicit": 4, "tooShort": 1 }. Tracks that cannot be played in the
+// host's market are dropped, and a few spare tracks are kept for
+// GenerateQuestions to replace the ones without distractors. Fewer than
+// model.MinTracks usable tracks or questions give a *notEnoughTracksError.
+// progress, if not nil, is passed to GenerateQuestions.
+func (h *Handler) generate(ctx context.Context, room model.Room, spec gameSpec, token string, progress func(done, total int)) (model.QuestionDraft, error) {
+	count := room.Settings.WithDefaults().QuestionCount
+	rules := newPoolRules(room)
+	var allTracks []model.Track
+	if spec.Mode == "mixed" {
+		allTracks = h.tracksFromSources(ctx, room, spec.Sources, candidateTracks(count), token, rules)
+	} else {
+		allTracks = h.sourceTracks(ctx, room, spec.Mode, spec.Query, token, rules)
+	}
+	if len(rules.removed) > 0 {
+		h.hub.Send(room.Code, room.HostId, "pool-filtered", rules.removed)
+	}
+	allTracks = h.playableTracks(ctx, allTracks, candidateTracks(count), token, room.Market)
+	if len(allTracks) < model.MinTracks(count) {
+		return model.QuestionDraft{}, &notEnoughTracksError{len(allTracks), model.MinTracks(count)}
+	}
+
+	// The sources come shuffled, with the preferred tracks first: players in
+	// turn, unplayed before played. Only the question order is random; the
+	// spares after the first count tracks stay in order of preference.
+	selected := allTracks[:min(count, len(allTracks))]
+	rand.Shuffle(len(selected), func(i, j int) {
+		selected[i], selected[j] = selected[j], selected[i]
+	})
+
+	questions, err := GenerateQuestions(ctx, h.spotify, allTracks, count, token, room.Market, progress)
+	if err != nil {
+		return model.QuestionDraft{}, err
+	}
+	if len(questions) < model.MinTracks(count) {
+		return model.QuestionDraft{}, &notEnoughTracksError{len(questions), model.MinTracks(count)}
+	}
+	return model.QuestionDraft{Questions: questions, RemovedTracks: rules.removed}, nil
+}
+
+// generationJob is a background generation for one room.
+type generationJob struct {
+	key    string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// generationJobs tracks the running background generation of every room.
+type generationJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*generationJob
+}
+
+func newGenerationJobs() *generationJobs {
+	return &generationJobs{jobs: make(map[string]*generationJob)}
+}
+
+// start runs run in the background as the room's job for key, cancelling
+// the room's job for another key. It returns false, and does nothing, if a
+// job for key is already running.
+func (j *generationJobs) start(roomCode, key string, run func(ctx context.Context)) bool {
+	j.mu.Lock()
+	if old := j.jobs[roomCode]; old != nil {
+		if old.key == key {
+			j.mu.Unlock()
+			return false
+		}
+		old.cancel()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), generationTimeout)
+	job := &generationJob{key: key, cancel: cancel, done: make(chan struct{})}
+	j.jobs[roomCode] = job
+	j.mu.Unlock()
+
+	go func() {
+		defer func() {
+			cancel()
+			j.mu.Lock()
+			if j.jobs[roomCode] == job {
+				delete(j.jobs, roomCode)
+			}
+			j.mu.Unlock()
+			close(job.done)
+		}()
+		run(ctx)
+	}()
+	return true
+}
+
+// wait blocks until the room's job for key is done. It returns at once if
+// no job for key is running.
+func (j *generationJobs) wait(ctx context.Context, roomCode, key string) {
+	j.mu.Lock()
+	job := j.jobs[roomCode]
+	j.mu.Unlock()
+	if job == nil || job.key != key {
+		return
+	}
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+	}
+}
+
+// cancel stops the room's running job, if any.
+func (j *generationJobs) cancel(roomCode string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if job := j.jobs[roomCode]; job != nil {
+		job.cancel()
+		delete(j.jobs, roomCode)
+	}
+}
+
+// PrepareGameHandler handles HTTP POST requests to /prepare-game.
+//
+// It takes the same payload and Authorization header as /start-game:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "hostId": "spotify-user-456",
+//	  "gameMode": "artist",
+//	  "tracksData": "1dfeR4HaWDbWqFHLkxsg1d",
+//	  "settings": { "questionCount": 10 }
+//	}
+//
+// The host calls it as soon as the game is picked, while the players are
+// still in the lobby. The mode, sources and settings are saved on the room
+// and the questions are generated in the background, so /start-game (with
+// just "roomCode" and "hostId") can start right away. Preparing another game
+// cancels the running generation and starts a new one. Since the pool of
+// "players" games depends on who is in the room, prepare again after players
+// join or submit songs.
+//
+// The host is kept up to date over the WebSocket:
+//
+//	{ "type": "generation-progress", "data": { "stage": "tracks" } }
+//	{ "type": "generation-progress", "data": { "stage": "questions", "done": 3, "total": 10 } }
+//	{ "type": "generation-ready", "data": { "questionsCount": 10, "removedTracks": { "explicit": 2 } } }
+//	{ "type": "generation-failed", "data": { "error": "not_enough_tracks", "message": "..." } }
+//
+// It responds with 202 and { "status": "preparing" }, or with
+// { "status": "ready" } if the questions for this game are already prepared.
+// The draft questions are kept for 60 minutes; /start-game generates the
+// questions itself if the room's players, game or settings have changed since.
+//
+// Error codes: "invalid_query" and "invalid_settings" (400), "room_not_found"
+// (404) and "invalid_state" (409) outside the lobby. A wrong hostId gets 403.
+func (h *Handler) PrepareGameHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.StartGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+		return
+	}
+	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if request.HostId != room.HostId {
+		http.Error(w, "Invalid HostId", http.StatusForbidden)
+		return
+	}
+	spec, err := resolveGame(request, room)
+	if err != nil {
+		writeGameError(w, err)
+		return
+	}
+	if request.Settings != nil {
+		if err := request.Settings.Validate(); err != nil {
+			apierror.Write(w, http.StatusBadRequest, "invalid_settings", err.Error())
+			return
+		}
+	}
+
+	market := h.hostMarket(r.Context(), room, token)
+	room, err = h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if room.State() != model.StateLobby {
+			return &model.TransitionError{From: room.State(), To: model.StateGenerating}
+		}
+		room.Market = market
+		room.GameMode = spec.Mode
+		room.QueryData = spec.Query
+		room.Sources = spec.Sources
+		if request.Settings != nil {
+			room.Settings = *request.Settings
+		}
+		return nil
+	})
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+
+	key := generationKey(room, spec)
+	if draft, err := h.repo.GetDraft(r.Context(), room.Code); err == nil && draft.Key == key {
+		json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+		return
+	}
+
+	h.jobs.start(room.Code, key, func(ctx context.Context) {
+		h.hub.Send(room.Code, room.HostId, "generation-progress", map[string]any{"stage": "tracks"})
+		draft, err := h.generate(ctx, room, spec, token, func(done, total int) {
+			h.hub.Send(room.Code, room.HostId, "generation-progress", map[string]any{
+				"stage": "questions",
+				"done":  done,
+				"total": total,
+			})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			code := "generation_failed"
+			var notEnough *notEnoughTracksError
+			if errors.As(err, &notEnough) {
+				code = "not_enough_tracks"
+			}
+			log.Printf("Background generation for room %s failed: %v", room.Code, err)
+			h.hub.Send(room.Code, room.HostId, "generation-failed", apierror.Response{Code: code, Message: err.Error()})
+			return
+		}
+
+		draft.Key = key
+		draft.CreatedAt = time.Now()
+		if err := h.repo.SaveDraft(ctx, room.Code, draft); err != nil {
+			log.Printf("Failed to save draft questions for room %s: %v", room.Code, err)
+			return
+		}
+		h.hub.Send(room.Code, room.HostId, "generation-ready", map[string]any{
+			"questionsCount": len(draft.Questions),
+			"removedTracks":  draft.RemovedTracks,
+		})
+	})
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "preparing"})
+}
@@ -4,18 +4,47 @@ import (
 	"backend/internal/lastfm"
 	"backend/internal/model"
 	"backend/internal/spotify"
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
+	"sync"
+	"time"
 )
 
-// GenerateQuestions generates quiz questions from a list of Spotify tracks.
+const (
+	// clipMs is how much of a track is played for a question.
+	clipMs = 15000
+	// distractorWorkers is how many tracks get their distractors at once.
+	distractorWorkers = 4
+	// distractorTimeout bounds every single Last.fm or Spotify lookup.
+	distractorTimeout = 5 * time.Second
+	// generationDeadline bounds a whole GenerateQuestions call. The questions
+	// that are ready by then are used.
+	generationDeadline = 45 * time.Second
+)
+
+// candidateTracks is how many tracks to pass to GenerateQuestions for count
+// questions: the spares replace tracks that get no distractors.
+func candidateTracks(count int) int {
+	return count + max(count/2, 2)
+}
+
+// GenerateQuestions generates up to count quiz questions from a list of
+// Spotify tracks.
 //
 // It expects:
-//   - a slice of model.Track structs containing metadata about tracks,
-//   - an OAuth access token to use for Spotify fallback search.
+//   - the Spotify client used for the fallback search,
+//   - a slice of model.Track structs containing metadata about tracks, in
+//     order of preference; tracks past the first count are spares,
+//   - the number of questions to generate,
+//   - an OAuth access token to use for Spotify fallback search,
+//   - the host's market, so the fallback only suggests songs available there,
+//   - an optional progress callback, called with the number of questions
+//     ready and count as the generation goes on.
 //
-// The function performs the following steps for each track:
+// The tracks are handled by 4 workers at once. The function performs the
+// following steps for each track:
 //
 // 1. Skips the track if it has an empty ID.
 //
@@ -23,24 +52,28 @@ import (
 //
 //  3. If Last.fm fails (either by error or empty result), it falls back to
 //     Spotify's search API using SimiliarFallback() to generate distractor answers.
+//     Each lookup is given 5 seconds.
 //
 // 4. If both methods fail to provide alternatives, the track is skipped.
 //
 //  5. Calculates a randomized playback start position for the track,
 //     choosing a moment between 0 and (duration - 15 seconds), ensuring
-//     there's at least a 15-second buffer from the end.
+//     there's at least a 15-second buffer from the end. Tracks shorter than
+//     that start at 0.
 //
 // 6. Constructs a model.Question object:
 //   - Adds the correct track name along with 3 distractor titles
 //   - Shuffles the answer options
-//   - Assigns a unique ID ("q1", "q2", etc)
 //   - Includes the playback position (in milliseconds)
 //
-// 7. Appends the question to the final result list.
+// Once the first count usable tracks are known, the remaining lookups are
+// cancelled. The questions keep the order of their tracks and get the IDs
+// "q1", "q2", etc. If the generation takes longer than 45 seconds, the
+// questions ready by then are returned.
 //
 // Returns:
 //   - A slice of model.Question ready for the quiz,
-//   - Or an error if critical steps fail.
+//   - Or an error if ctx is cancelled.
 //
 // Example response:
 //
@@ -55,45 +88,128 @@ import (
 //	  },
 //	  ...
 //	]
-func GenerateQuestions(tracks []model.Track, token string) ([]model.Question, error) {
-	var questions []model.Question
-	for i, track := range tracks {
-		var question model.Question
+func GenerateQuestions(ctx context.Context, client *spotify.Client, tracks []model.Track, count int, token, market string, progress func(done, total int)) ([]model.Question, error) {
+	genCtx, cancel := context.WithTimeout(ctx, generationDeadline)
+	defer cancel()
 
-		if track.ID == "" {
-			log.Printf("Skipping empty track ID for %s", track.Name)
-			continue
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range tracks {
+			select {
+			case indexes <- i:
+			case <-genCtx.Done():
+				return
+			}
 		}
+	}()
 
-		recommendations, err := lastfm.FetchSimilar(track)
-		if err != nil || len(recommendations) == 0 {
-			log.Printf("Last.fm failed for track %s: %v — trying fallback", track.ID, err)
-			recommendations, err = spotify.SimiliarFallback(track, token)
-			if err != nil || len(recommendations) == 0 {
-				log.Printf("Fallback also failed for track %s: %v", track.ID, err)
-				continue
+	type result struct {
+		index    int
+		question *model.Question
+	}
+	results := make(chan result)
+	var wg sync.WaitGroup
+	for range min(distractorWorkers, len(tracks)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results <- result{i, buildQuestion(genCtx, client, tracks[i], token, market)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// The tracks are handed out in order, so once every track up to front is
+	// done and count of them are usable, no later track can make the cut.
+	built := make([]*model.Question, len(tracks))
+	done := make([]bool, len(tracks))
+	front, usable, ready := 0, 0, 0
+	for result := range results {
+		done[result.index] = true
+		built[result.index] = result.question
+		if result.question != nil {
+			ready++
+			if progress != nil {
+				progress(min(ready, count), count)
 			}
 		}
+		for front < len(tracks) && done[front] {
+			if built[front] != nil {
+				usable++
+			}
+			front++
+		}
+		if usable >= count {
+			cancel()
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if usable < count && genCtx.Err() != nil {
+		log.Printf("Question generation hit the %s deadline with %d of %d questions", generationDeadline, ready, count)
+	}
 
-		trackDuration := track.Duration // w ms
-		maxStart := trackDuration - 15000
-		maxStart = max(maxStart, 0)
-		startMs := rand.Intn(maxStart)
+	var questions []model.Question
+	for _, question := range built {
+		if question == nil || len(questions) == count {
+			continue
+		}
+		question.ID = fmt.Sprintf("q%d", len(questions)+1)
+		questions = append(questions, *question)
+	}
+	return questions, nil
+}
 
-		question.ID = fmt.Sprintf("q%d", i+1)
-		question.TrackID = track.ID
-		question.TrackName = track.Name
-		question.AnswerOptions = recommendations
-		question.AnswerOptions = append(question.AnswerOptions, track.Name)
-		question.CorrectAnswer = track.Name
-		question.PositionMs = startMs
-		rand.Shuffle(len(question.AnswerOptions), func(i, j int) {
-			question.AnswerOptions[i], question.AnswerOptions[j] = question.AnswerOptions[j], question.AnswerOptions[i]
-		})
+// buildQuestion makes the question of one track, or returns nil if the track
+// gets no distractors.
+func buildQuestion(ctx context.Context, client *spotify.Client, track model.Track, token, market string) *model.Question {
+	if track.ID == "" {
+		log.Printf("Skipping empty track ID for %s", track.Name)
+		return nil
+	}
 
-		questions = append(questions, question)
+	lookupCtx, cancel := context.WithTimeout(ctx, distractorTimeout)
+	recommendations, err := lastfm.FetchSimilar(lookupCtx, track)
+	cancel()
+	if err != nil || len(recommendations) == 0 {
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("Last.fm failed for track %s: %v — trying fallback", track.ID, err)
+		lookupCtx, cancel := context.WithTimeout(ctx, distractorTimeout)
+		recommendations, err = client.SimiliarFallback(lookupCtx, track, token, market)
+		cancel()
+		if err != nil || len(recommendations) == 0 {
+			if ctx.Err() == nil {
+				log.Printf("Fallback also failed for track %s: %v", track.ID, err)
+			}
+			return nil
+		}
 	}
 
-	return questions, nil
+	trackDuration := track.Duration // w ms
+	maxStart := trackDuration - clipMs
+	startMs := 0
+	if maxStart > 0 {
+		startMs = rand.Intn(maxStart)
+	}
 
+	question := model.Question{
+		TrackID:       track.ID,
+		TrackName:     track.Name,
+		AnswerOptions: append(recommendations, track.Name),
+		CorrectAnswer: track.Name,
+		PositionMs:    startMs,
+		SubmittedBy:   track.SubmittedBy,
+	}
+	rand.Shuffle(len(question.AnswerOptions), func(i, j int) {
+		question.AnswerOptions[i], question.AnswerOptions[j] = question.AnswerOptions[j], question.AnswerOptions[i]
+	})
+	return &question
 }
@@ -2,170 +2,140 @@ package game
 
 import (
 	"backend/internal/model"
-	"backend/internal/store"
-	"backend/internal/ws"
-	"encoding/json"
-	"fmt"
+	"context"
 	"log"
-	"strconv"
 	"time"
 )
 
 // RunQuizLoop runs the automated quiz loop for a given room.
 //
 // This function is executed asynchronously (as a goroutine) after /start-game is called.
+// startsAt is the end of the pre-game countdown announced to the clients.
 // It handles the full lifecycle of the quiz by broadcasting questions and scoreboards
 // via WebSocket to all clients in the room, with delays between rounds.
 //
 // It performs the following steps:
 //
-//  1. Waits 2 seconds before starting (to ensure clients are ready).
+//  1. Waits until startsAt, so the first question arrives when the countdown ends.
 //
-//  2. Retrieves the Room object from Redis ("room:{roomCode}").
+//  2. Retrieves the Room object from the repository ("room:{roomCode}").
 //
-//  3. Retrieves the generated []Question from Redis ("questions:{roomCode}").
+//  3. Retrieves the generated []Question from the repository ("questions:{roomCode}").
 //
 //  4. Iterates over each question:
-//     a. Broadcasts a WebSocket message:
+//     a. Waits while the room is waiting for a host (the host disconnected and
+//     nobody could take over), polling once per second.
+//     b. Moves the room to "in-round" and updates its CurrentQIdx with an atomic
+//     room update, refreshing the local copy of the room so players who joined
+//     meanwhile are kept.
+//     c. Broadcasts a WebSocket message:
 //
 //     {
 //     "type": "question",
 //     "data": { ...question }
 //     }
 //
-//     b. Increments and updates the room's CurrentQIdx in Redis.
-//     c. Waits x seconds for players to answer.
-//     d. Gathers scores for each player from Redis ("score:{roomCode}:{playerId}").
-//     e. Broadcasts the scoreboard:
+//     d. Waits the room's answer time (settings.answerSeconds, default 15) for
+//     players to answer, then moves the room to "reveal".
+//     e. Gathers scores for each player from Redis ("score:{roomCode}:{playerId}").
+//     f. Broadcasts the scoreboard, highest score first:
 //
 //     {
 //     "type": "scoreboard",
-//     "data": { "player1": 2000, "guest:xyz": 1000 }
+//     "data": [
+//     { "playerId": "p-3f9a0c12de", "displayName": "DJ Kasia", "avatar": "...", "score": 2000 },
+//     { "playerId": "guest", "displayName": "guest", "avatar": "...", "score": 1000 }
+//     ]
 //     }
 //
-//     f. Waits another x seconds before continuing.
+//     g. Waits the room's reveal time (settings.revealSeconds, default 5) before continuing.
 //
-//  5. After all questions, broadcasts a final message:
+//  5. After all questions, moves the room to "finished", adds the scores to the
+//     room's series totals and broadcasts a final message:
 //
 //     {
-//     "type": "game-over"
+//     "type": "game-over",
+//     "data": [ ...final scoreboard, as above ]
 //     }
 //
-//  6. Cleans up by deleting all room-related keys from Redis:
-//     - "room:{roomCode}"
-//     - "questions:{roomCode}"
-//     - "tracks:{roomCode}:{playerId}"
-//     - "score:{roomCode}:{playerId}"
+//  6. Builds the per-question and per-player statistics, stores them under
+//     "summary:{roomCode}" for RoomTTL plus SUMMARY_TTL (SUMMARY_TTL once the
+//     room is closed) and broadcasts them as "game-summary".
 //
-// This function assumes all track, question, and score data exists and is valid.
-// It logs any Redis or decode failures and continues where possible.
-func RunQuizLoop(roomCode string) {
-	time.Sleep(2 * time.Second)
+//  7. Deletes the per-game keys ("questions:{roomCode}", "answers:{roomCode}:{questionId}").
+//     The room, scores and tracks are kept for a rematch until the host closes the room.
+//
+// Every state change is broadcast as "state-changed". If the room or its
+// questions cannot be loaded, or the room can no longer move to the next state
+// (e.g. it was closed), the loop logs the error and stops.
+// Other storage failures are logged and the loop continues where possible.
+func (h *Handler) RunQuizLoop(roomCode string, startsAt time.Time) {
+	ctx := context.Background()
+	time.Sleep(time.Until(startsAt))
 	log.Println("Starting quiz loop for room:", roomCode)
 
-	roomKey := "room:" + roomCode
-	data, err := store.Client.Get(store.Ctx, roomKey).Result()
-	if err != nil {
-		panic(err)
-	}
-
-	var room model.Room
-	err = json.Unmarshal([]byte(data), &room)
-	if err != nil {
-		panic(err)
-	}
-
-	questionsKey := "questions:" + roomCode
-	data, err = store.Client.Get(store.Ctx, questionsKey).Result()
+	room, err := h.repo.GetRoom(ctx, roomCode)
 	if err != nil {
-		panic(err)
+		log.Println("quiz loop: failed to load room:", err)
+		return
 	}
 
-	var questions []model.Question
-	err = json.Unmarshal([]byte(data), &questions)
+	questions, err := h.repo.GetQuestions(ctx, roomCode)
 	if err != nil {
-		panic(err)
+		log.Println("quiz loop: failed to load questions:", err)
+		return
 	}
 
 	log.Printf("Room has %d players, %d questions", len(room.Players), len(questions))
+	settings := room.Settings.WithDefaults()
 
 	for i, question := range questions {
-		log.Printf("Broadcasting question %d", i+1)
-
-		message := map[string]any{
-			"type": "question",
-			"data": question,
-		}
-
-		payload, _ := json.Marshal(message)
-		timestampKey := fmt.Sprintf("question-time:%s:%s", roomCode, question.ID)
-		store.Client.Set(store.Ctx, timestampKey, time.Now().UnixMilli(), 60*time.Minute)
-		ws.GlobalHub.Broadcast <- ws.BroadcastMessage{
-			RoomCode: roomCode,
-			Data:     payload,
-		}
-		room.CurrentQIdx = i + 1
-		roomData, _ := json.Marshal(room)
-		store.Client.Set(store.Ctx, roomKey, roomData, 60*time.Minute)
-
-		time.Sleep(15 * time.Second)
-
-		scoreboard := make(map[string]int)
-		for _, player := range room.Players {
-			scoreKey := "score:" + roomCode + ":" + player
-			data, err = store.Client.Get(store.Ctx, scoreKey).Result()
-
-			var score int
-			score, err = strconv.Atoi(data)
-			if err != nil {
-				log.Printf("invalid score for player %s: %v", player, err)
-				score = 0
-			}
-
-			scoreboard[player] = score
+		if err := h.waitForHost(ctx, roomCode); err != nil {
+			log.Println("quiz loop: stopping, room is gone while waiting for a host:", err)
+			return
 		}
+		log.Printf("Broadcasting question %d", i+1)
 
-		message = map[string]any{
-			"type": "scoreboard",
-			"data": scoreboard,
+		room, err = h.transition(ctx, roomCode, model.StateInRound, func(room *model.Room) error {
+			room.CurrentQIdx = i + 1
+			return nil
+		})
+		if err != nil {
+			log.Println("quiz loop: stopping, cannot start round:", err)
+			return
 		}
 
-		payload, _ = json.Marshal(message)
-		ws.GlobalHub.Broadcast <- ws.BroadcastMessage{RoomCode: roomCode, Data: payload}
+		h.repo.SetQuestionTime(ctx, roomCode, question.ID, time.Now())
+		h.hub.Emit(roomCode, "question", question)
 
-		store.Client.Del(store.Ctx, timestampKey)
-		time.Sleep(5 * time.Second)
-	}
-	scoreboard := make(map[string]int)
-	for _, player := range room.Players {
-		scoreKey := "score:" + roomCode + ":" + player
-		data, err = store.Client.Get(store.Ctx, scoreKey).Result()
+		time.Sleep(time.Duration(settings.AnswerSeconds) * time.Second)
 
-		var score int
-		score, err = strconv.Atoi(data)
+		room, err = h.transition(ctx, roomCode, model.StateReveal, nil)
 		if err != nil {
-			log.Printf("invalid score for player %s: %v", player, err)
-			score = 0
+			log.Println("quiz loop: stopping, cannot reveal round:", err)
+			return
 		}
 
-		scoreboard[player] = score
-	}
+		scoreboard := h.collectScoreboard(ctx, roomCode, room.Players)
+		h.hub.Emit(roomCode, "scoreboard", rankScoreboard(room, scoreboard))
 
-	message := map[string]any{
-		"type": "game-over",
-		"data": scoreboard,
-	}
-	payload, _ := json.Marshal(message)
-	ws.GlobalHub.Broadcast <- ws.BroadcastMessage{
-		RoomCode: roomCode,
-		Data:     payload,
+		h.repo.DeleteQuestionTime(ctx, roomCode, question.ID)
+		time.Sleep(time.Duration(settings.RevealSeconds) * time.Second)
 	}
-	store.Client.Del(store.Ctx, "room:"+roomCode)
-	store.Client.Del(store.Ctx, "questions:"+roomCode)
 
-	for _, player := range room.Players {
-		store.Client.Del(store.Ctx, "score:"+roomCode+":"+player)
-		store.Client.Del(store.Ctx, "tracks:"+roomCode+":"+player)
+	if err := h.finishGame(roomCode, questions); err != nil {
+		log.Println("quiz loop: failed to finish game:", err)
 	}
+}
 
+// waitForHost blocks while the room is waiting for a host. It returns an
+// error if the room can no longer be loaded, e.g. because it expired.
+func (h *Handler) waitForHost(ctx context.Context, roomCode string) error {
+	for {
+		room, err := h.repo.GetRoom(ctx, roomCode)
+		if err != nil || !room.WaitingForHost {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
 }
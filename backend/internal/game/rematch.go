@@ -0,0 +1,203 @@
+package game
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// PlayAgainHandler handles HTTP POST requests to /play-again.
+//
+// It expects a JSON payload in the following format:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "hostId": "spotify-user-456",
+//	  "gameMode": "playlist",               // optional
+//	  "tracksData": "37i9dQZF1DXcBWIGoYBM5M", // optional
+//	  "sources": [{ "mode": "players" }],   // optional, see /start-game
+//	  "settings": { "questionCount": 15 }   // optional
+//	}
+//
+// The handler performs the following steps:
+//
+//  1. Verifies that the requesting user (hostId) matches the room's HostId.
+//
+//  2. Validates the optional game mode, query ("invalid_query") and settings.
+//
+//  3. Moves the room from "finished" back to "lobby", keeping its players and
+//     their cached tracks. CurrentQIdx, the ready check and the submitted songs
+//     are reset and the optional game mode, query and settings replace the
+//     previous ones.
+//
+//  4. Resets every player's score to 0. The running series totals stay on the room.
+//
+//  5. Broadcasts a "rematch" message via WebSocket:
+//
+//     {
+//     "type": "rematch",
+//     "data": {
+//     "gameNumber": 2,
+//     "series": { "player1": 8120, "player2": 6400 },
+//     "gameMode": "playlist",
+//     "settings": { "questionCount": 15, "answerSeconds": 15, "revealSeconds": 5 }
+//     }
+//     }
+//
+//  6. Responds with the same data. The host then starts the next game with /start-game.
+//
+// If the room is not finished, responds with 409 and the "invalid_state" code.
+func (h *Handler) PlayAgainHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.PlayAgainRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if request.HostId != room.HostId {
+		http.Error(w, "Invalid HostId", http.StatusForbidden)
+		return
+	}
+	if len(request.Sources) > 0 {
+		if err := model.ValidateSources(request.Sources); err != nil {
+			apierror.Write(w, http.StatusBadRequest, "invalid_query", err.Error())
+			return
+		}
+	} else if request.GameMode != "" {
+		if !model.ValidGameMode(request.GameMode) {
+			http.Error(w, "Unsupported game mode", http.StatusBadRequest)
+			return
+		}
+		if err := model.ValidateQuery(request.GameMode, request.QueryData); err != nil {
+			apierror.Write(w, http.StatusBadRequest, "invalid_query", err.Error())
+			return
+		}
+	}
+	if request.Settings != nil {
+		if err := request.Settings.Validate(); err != nil {
+			apierror.Write(w, http.StatusBadRequest, "invalid_settings", err.Error())
+			return
+		}
+	}
+
+	room, err = h.transition(r.Context(), request.RoomCode, model.StateLobby, func(room *model.Room) error {
+		room.CurrentQIdx = 0
+		room.Ready = nil
+		room.Submissions = nil
+		switch {
+		case len(request.Sources) > 0:
+			room.GameMode = "mixed"
+			room.QueryData = ""
+			room.Sources = request.Sources
+		case request.GameMode != "":
+			room.GameMode = request.GameMode
+			room.QueryData = request.QueryData
+			room.Sources = nil
+		}
+		if request.Settings != nil {
+			room.Settings = *request.Settings
+		}
+		return nil
+	})
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+
+	for _, player := range room.Players {
+		if err := h.repo.DeleteScore(r.Context(), room.Code, player); err != nil {
+			log.Printf("Failed to reset score for player %s: %v", player, err)
+		}
+	}
+
+	rematch := map[string]any{
+		"gameNumber": room.GamesPlayed + 1,
+		"series":     room.Series,
+		"gameMode":   room.GameMode,
+		"tracksData": room.QueryData,
+		"sources":    room.Sources,
+		"settings":   room.Settings.WithDefaults(),
+	}
+	h.hub.Emit(room.Code, "rematch", rematch)
+	json.NewEncoder(w).Encode(rematch)
+}
+
+// CloseRoomHandler handles HTTP POST requests to /close-room.
+//
+// It expects a JSON payload in the following format:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "hostId": "spotify-user-456"
+//	}
+//
+// Only the host may close the room, and only while no game is being played
+// (the room is in "lobby" or "finished"). The room is moved to "closed", which
+// is broadcast as "state-changed", and all of its data is deleted. The game
+// summary stays available for SUMMARY_TTL from now on.
+//
+//	Response:
+//	{
+//	  "status": "closed"
+//	}
+func (h *Handler) CloseRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.CloseRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err != nil {
+		writeStateError(w, err)
+		return
+	}
+	if request.HostId != room.HostId {
+		http.Error(w, "Invalid HostId", http.StatusForbidden)
+		return
+	}
+	if state := room.State(); state != model.StateLobby && state != model.StateFinished {
+		apierror.Write(w, http.StatusConflict, "invalid_state", "The room cannot be closed while a game is running")
+		return
+	}
+
+	if err := h.closeRoom(r.Context(), request.RoomCode); err != nil {
+		writeStateError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "closed",
+	})
+}
+
+// closeRoom moves the room to "closed" and deletes the room together with
+// its questions, chat history and every player's score and cached tracks.
+// The game summary expires summaryTTL from now.
+func (h *Handler) closeRoom(ctx context.Context, roomCode string) error {
+	room, err := h.transition(ctx, roomCode, model.StateClosed, nil)
+	if err != nil {
+		return err
+	}
+
+	h.jobs.cancel(roomCode)
+	h.repo.DeleteRoom(ctx, roomCode)
+	h.repo.DeleteQuestions(ctx, roomCode)
+	h.repo.DeleteDraft(ctx, roomCode)
+	h.repo.DeleteChat(ctx, roomCode)
+	if err := h.repo.ExpireSummary(ctx, roomCode, summaryTTL()); err != nil {
+		log.Println("Failed to expire game summary:", err)
+	}
+	for _, player := range room.Players {
+		h.repo.DeleteScore(ctx, roomCode, player)
+		h.repo.DeleteTracks(ctx, roomCode, player)
+	}
+	return nil
+}
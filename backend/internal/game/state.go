@@ -0,0 +1,76 @@
+package game
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"backend/internal/store"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// transition atomically moves the room to the given state, applies the
+// optional extra update in the same write and broadcasts "state-changed":
+//
+//	{
+//	  "type": "state-changed",
+//	  "data": { "state": "in-round", "previous": "generating" }
+//	}
+//
+// It returns a *model.TransitionError if the move is not allowed.
+func (h *Handler) transition(ctx context.Context, roomCode string, to model.GameState, extra store.RoomUpdate) (model.Room, error) {
+	var previous model.GameState
+	room, err := h.repo.UpdateRoom(ctx, roomCode, func(room *model.Room) error {
+		previous = room.State()
+		if err := room.Transition(to); err != nil {
+			return err
+		}
+		if extra != nil {
+			return extra(room)
+		}
+		return nil
+	})
+	if err != nil {
+		return room, err
+	}
+
+	h.hub.Emit(roomCode, "state-changed", map[string]any{
+		"state":    to,
+		"previous": previous,
+	})
+	return room, nil
+}
+
+// rollbackToLobby moves a room whose game failed to start back to "lobby".
+// It runs even if the request was cancelled, so the room is never left in
+// "generating".
+func (h *Handler) rollbackToLobby(ctx context.Context, roomCode string) {
+	if _, err := h.transition(context.WithoutCancel(ctx), roomCode, model.StateLobby, nil); err != nil {
+		log.Printf("Failed to move room %s back to the lobby: %v", roomCode, err)
+	}
+}
+
+// writeStateError writes the HTTP error for a failed room update. Invalid
+// state transitions become 409 responses with the "invalid_state" code.
+func writeStateError(w http.ResponseWriter, err error) {
+	var transitionErr *model.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		apierror.Write(w, http.StatusConflict, "invalid_state", transitionErr.Error())
+	case errors.Is(err, store.ErrNotFound):
+		apierror.Write(w, http.StatusNotFound, "room_not_found", "Room not found")
+	case errors.Is(err, store.ErrConflict):
+		apierror.Write(w, http.StatusConflict, "room_busy", "Room is busy, please try again")
+	default:
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
+// writeNotEnoughTracks responds with 422 ("not_enough_tracks") when the track
+// pool is too small for a game.
+func writeNotEnoughTracks(w http.ResponseWriter, found, needed int) {
+	apierror.Write(w, http.StatusUnprocessableEntity, "not_enough_tracks",
+		fmt.Sprintf("Found %d usable tracks, at least %d are needed", found, needed))
+}
@@ -0,0 +1,327 @@
+package game
+
+import (
+	"backend/internal/model"
+	"backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultSummaryTTL = 24 * time.Hour
+
+// summaryTTL returns how long a game summary is kept after the room closes or
+// expires. It is read from the SUMMARY_TTL environment variable (e.g. "2h",
+// "30m") and falls back to 24 hours when unset or invalid.
+func summaryTTL() time.Duration {
+	raw := os.Getenv("SUMMARY_TTL")
+	if raw == "" {
+		return defaultSummaryTTL
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		log.Printf("invalid SUMMARY_TTL %q, using default", raw)
+		return defaultSummaryTTL
+	}
+	return ttl
+}
+
+// collectScoreboard reads every player's score from the repository.
+// Missing or invalid scores are reported as 0.
+func (h *Handler) collectScoreboard(ctx context.Context, roomCode string, players []string) map[string]int {
+	scoreboard := make(map[string]int)
+	for _, player := range players {
+		score, err := h.repo.GetScore(ctx, roomCode, player)
+		if err != nil {
+			log.Printf("invalid score for player %s: %v", player, err)
+			score = 0
+		}
+
+		scoreboard[player] = score
+	}
+	return scoreboard
+}
+
+// rankScoreboard turns a scoreboard into entries carrying each player's
+// display name and avatar, highest score first. Ties keep the join order.
+func rankScoreboard(room model.Room, scoreboard map[string]int) []model.ScoreEntry {
+	entries := make([]model.ScoreEntry, 0, len(scoreboard))
+	for _, player := range room.Players {
+		score, ok := scoreboard[player]
+		if !ok {
+			continue
+		}
+		profile := room.Profile(player)
+		entries = append(entries, model.ScoreEntry{
+			PlayerID:    player,
+			DisplayName: profile.DisplayName,
+			Avatar:      profile.Avatar,
+			Score:       score,
+		})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Score > entries[j].Score
+	})
+	return entries
+}
+
+// loadAnswers reads the recorded answers of every question, keyed by question ID.
+func (h *Handler) loadAnswers(ctx context.Context, roomCode string, questions []model.Question) map[string][]model.AnswerRecord {
+	answers := make(map[string][]model.AnswerRecord)
+	for _, question := range questions {
+		records, err := h.repo.GetAnswers(ctx, roomCode, question.ID)
+		if err != nil {
+			log.Printf("failed to fetch answers for question %s: %v", question.ID, err)
+			continue
+		}
+		answers[question.ID] = records
+	}
+	return answers
+}
+
+// buildSummary assembles the post-game statistics from the recorded answers.
+//
+// For every question it lists who answered correctly, the fastest correct
+// answer and how the answers were spread across the options. For every player
+// it computes accuracy (correct / number of questions) and the average
+// response time of the answers they gave.
+func buildSummary(roomCode string, players []string, questions []model.Question, answers map[string][]model.AnswerRecord, scoreboard map[string]int) model.GameSummary {
+	summary := model.GameSummary{
+		RoomCode:   roomCode,
+		FinishedAt: time.Now(),
+		Questions:  make([]model.QuestionStats, 0, len(questions)),
+		Scoreboard: scoreboard,
+	}
+
+	perPlayer := make(map[string]*model.PlayerStats)
+	totalResponse := make(map[string]int64)
+	for _, player := range players {
+		perPlayer[player] = &model.PlayerStats{PlayerID: player, Score: scoreboard[player]}
+	}
+
+	for _, question := range questions {
+		stats := model.QuestionStats{
+			QuestionID:     question.ID,
+			TrackID:        question.TrackID,
+			TrackName:      question.TrackName,
+			CorrectAnswer:  question.CorrectAnswer,
+			CorrectPlayers: []string{},
+			OptionCounts:   make(map[string]int),
+		}
+		for _, option := range question.AnswerOptions {
+			stats.OptionCounts[option] = 0
+		}
+
+		for _, answer := range answers[question.ID] {
+			stats.Answered++
+			stats.OptionCounts[answer.Selected]++
+
+			player, ok := perPlayer[answer.PlayerID]
+			if !ok {
+				player = &model.PlayerStats{PlayerID: answer.PlayerID, Score: scoreboard[answer.PlayerID]}
+				perPlayer[answer.PlayerID] = player
+			}
+			player.Answered++
+			totalResponse[answer.PlayerID] += answer.ResponseMs
+
+			if !answer.Correct {
+				continue
+			}
+			player.Correct++
+			stats.CorrectPlayers = append(stats.CorrectPlayers, answer.PlayerID)
+			if stats.FastestPlayer == "" || answer.ResponseMs < stats.FastestMs {
+				stats.FastestPlayer = answer.PlayerID
+				stats.FastestMs = answer.ResponseMs
+			}
+		}
+
+		sort.Strings(stats.CorrectPlayers)
+		summary.Questions = append(summary.Questions, stats)
+	}
+
+	for id, player := range perPlayer {
+		if len(questions) > 0 {
+			player.Accuracy = float64(player.Correct) / float64(len(questions))
+		}
+		if player.Answered > 0 {
+			player.AvgResponseMs = totalResponse[id] / int64(player.Answered)
+		}
+		summary.Players = append(summary.Players, *player)
+	}
+	sort.Slice(summary.Players, func(i, j int) bool {
+		if summary.Players[i].Score != summary.Players[j].Score {
+			return summary.Players[i].Score > summary.Players[j].Score
+		}
+		return summary.Players[i].PlayerID < summary.Players[j].PlayerID
+	})
+
+	return summary
+}
+
+// buildGameRecord converts a finished game into the record kept by the history store.
+// Players are ranked by score; players with equal scores share a rank.
+func buildGameRecord(room model.Room, questions []model.Question, answers map[string][]model.AnswerRecord, summary model.GameSummary) model.GameRecord {
+	record := model.GameRecord{
+		ID:         fmt.Sprintf("%s-%d", room.Code, summary.FinishedAt.UnixMilli()),
+		RoomCode:   room.Code,
+		HostID:     room.HostId,
+		GameMode:   room.GameMode,
+		QueryData:  room.QueryData,
+		FinishedAt: summary.FinishedAt,
+		Questions:  questions,
+		Answers:    answers,
+	}
+
+	seen := make(map[string]bool)
+	for i, player := range summary.Players {
+		rank := i + 1
+		if i > 0 && player.Score == summary.Players[i-1].Score {
+			rank = record.Ranking[i-1].Rank
+		}
+
+		// A Spotify account that joined twice is recorded once under its ID.
+		spotifyID := room.SpotifyIDs[player.PlayerID]
+		playerKey := spotifyID
+		if playerKey == "" || seen[playerKey] {
+			playerKey = model.GuestKey(record.ID, i)
+		}
+		seen[playerKey] = true
+
+		record.Ranking = append(record.Ranking, model.RankingEntry{
+			PlayerKey:     playerKey,
+			PlayerID:      player.PlayerID,
+			SpotifyID:     spotifyID,
+			Rank:          rank,
+			Score:         player.Score,
+			Correct:       player.Correct,
+			Answered:      player.Answered,
+			AvgResponseMs: player.AvgResponseMs,
+		})
+	}
+	return record
+}
+
+// finishGame ends the quiz for a room.
+//
+// It moves the room to "finished" and adds the final scores to the room's
+// series totals, broadcasts the final "game-over" scoreboard (as ranked
+// []ScoreEntry with display names), builds the game
+// summary, stores it under "summary:{roomCode}" for RoomTTL plus SUMMARY_TTL
+// and broadcasts it as "game-summary". The game is then saved to the history store and the
+// per-game keys (questions, answers) are deleted.
+//
+// The room, its players, their scores and cached tracks are kept so the host
+// can start a rematch with /play-again or end the session with /close-room.
+//
+// It returns the transition error if the room cannot be finished from its current state.
+func (h *Handler) finishGame(roomCode string, questions []model.Question) error {
+	ctx := context.Background()
+	room, err := h.repo.GetRoom(ctx, roomCode)
+	if err != nil {
+		return err
+	}
+	scoreboard := h.collectScoreboard(ctx, roomCode, room.Players)
+
+	room, err = h.transition(ctx, roomCode, model.StateFinished, func(room *model.Room) error {
+		if room.Series == nil {
+			room.Series = make(map[string]int)
+		}
+		for player, score := range scoreboard {
+			room.Series[player] += score
+		}
+		room.GamesPlayed++
+		played := make([]string, 0, len(questions))
+		for _, question := range questions {
+			played = append(played, question.TrackID)
+		}
+		room.RecordPlayedTracks(played)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	h.hub.Emit(roomCode, "game-over", rankScoreboard(room, scoreboard))
+
+	answers := h.loadAnswers(ctx, roomCode, questions)
+	summary := buildSummary(roomCode, room.Players, questions, answers, scoreboard)
+	summary.Series = room.Series
+	summary.GameNumber = room.GamesPlayed
+	for i := range summary.Players {
+		summary.Players[i].DisplayName = room.Profile(summary.Players[i].PlayerID).DisplayName
+	}
+	// Rooms that are never closed lapse after RoomTTL and the summary
+	// outlives them by SUMMARY_TTL; closeRoom cuts it down to SUMMARY_TTL.
+	if err := h.repo.SaveSummary(ctx, summary, store.RoomTTL+summaryTTL()); err != nil {
+		log.Println("Failed to save game summary:", err)
+	}
+	h.hub.Emit(roomCode, "game-summary", summary)
+
+	if h.history != nil {
+		historyCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		err = h.history.RecordGame(historyCtx, buildGameRecord(room, questions, answers, summary))
+		cancel()
+		if err != nil {
+			log.Println("Failed to record game history:", err)
+		}
+	}
+
+	h.repo.DeleteQuestions(ctx, roomCode)
+	for _, question := range questions {
+		h.repo.DeleteAnswers(ctx, roomCode, question.ID)
+		h.repo.DeleteQuestionTime(ctx, roomCode, question.ID)
+	}
+	return nil
+}
+
+// GetSummaryHandler handles HTTP GET requests to /room/{code}/summary.
+//
+// It returns the GameSummary stored under "summary:{roomCode}" when the game ended:
+//
+//	Response:
+//	{
+//	  "roomCode": "ABC123",
+//	  "finishedAt": "...",
+//	  "questions": [
+//	    {
+//	      "questionId": "q1",
+//	      "trackName": "Shape of You",
+//	      "correctPlayers": ["player1"],
+//	      "fastestPlayer": "player1",
+//	      "fastestMs": 2140,
+//	      "optionCounts": { "Shape of You": 1, "Photograph": 1 },
+//	      ...
+//	    }
+//	  ],
+//	  "players": [
+//	    { "playerId": "player1", "score": 940, "accuracy": 1, "avgResponseMs": 2140, ... }
+//	  ],
+//	  "scoreboard": { "player1": 940, "player2": 0 }
+//	}
+//
+// If no summary exists (the game has not finished or the summary expired), responds with 404.
+func (h *Handler) GetSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 3 || parts[2] == "" {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	summary, err := h.repo.GetSummary(r.Context(), parts[2])
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Summary not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to load summary", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(summary)
+}
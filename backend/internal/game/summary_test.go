@@ -0,0 +1,44 @@
+package game
+
+import (
+	"backend/internal/model"
+	"testing"
+	"time"
+)
+
+func TestBuildGameRecordPlayerKeys(t *testing.T) {
+	room := model.Room{
+		Code: "ABC123",
+		// "twin" joined twice with the same Spotify account.
+		SpotifyIDs: map[string]string{"p1": "spotify-1", "twin": "spotify-1"},
+	}
+	summary := model.GameSummary{
+		FinishedAt: time.Unix(1000, 0),
+		Players: []model.PlayerStats{
+			{PlayerID: "p1", Score: 900},
+			{PlayerID: "twin", Score: 800},
+			{PlayerID: "guest", Score: 800},
+			{PlayerID: "Guest", Score: 100},
+		},
+	}
+	record := buildGameRecord(room, nil, nil, summary)
+
+	keys := make(map[string]bool)
+	for _, entry := range record.Ranking {
+		if keys[entry.PlayerKey] {
+			t.Fatalf("player key %q used twice", entry.PlayerKey)
+		}
+		keys[entry.PlayerKey] = true
+	}
+	if record.Ranking[0].PlayerKey != "spotify-1" {
+		t.Errorf("Spotify player key = %q, want the Spotify ID", record.Ranking[0].PlayerKey)
+	}
+	for _, entry := range record.Ranking[1:] {
+		if !model.IsGuestKey(entry.PlayerKey) {
+			t.Errorf("key of %s = %q, want a guest key", entry.PlayerID, entry.PlayerKey)
+		}
+	}
+	if record.Ranking[1].Rank != 2 || record.Ranking[2].Rank != 2 || record.Ranking[3].Rank != 4 {
+		t.Errorf("ranks = %+v, want equal scores to share a rank", record.Ranking)
+	}
+}
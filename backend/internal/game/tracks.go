@@ -2,177 +2,126 @@ package game
 
 import (
 	"backend/internal/model"
-	"backend/internal/store"
-	"encoding/json"
-	"fmt"
+	"context"
 	"log"
 	"math/rand"
-	"net/http"
 )
 
-func tracksFromPlayers(players []string, roomCode string) []model.Track {
-	var allTracks []model.Track
+// refreshPlayerTracks fetches the tracks of every player with a stored
+// Spotify token again, from the track source in the game settings. The host
+// may have changed the source since the players joined. When a fetch fails,
+// the tracks saved on join are kept.
+func (h *Handler) refreshPlayerTracks(ctx context.Context, players []string, roomCode string, settings model.GameSettings, market string) {
 	for _, playerID := range players {
-		key := fmt.Sprintf("tracks:%s:%s", roomCode, playerID)
-		raw, err := store.Client.Get(store.Ctx, key).Result()
+		token, err := h.repo.GetPlayerToken(ctx, playerID)
 		if err != nil {
-			log.Println("no tracks for player:", playerID, err)
 			continue
 		}
-
-		var tracks []model.Track
-		err = json.Unmarshal([]byte(raw), &tracks)
-		if err != nil {
-			log.Println("invalid track data for player:", playerID, err)
+		tracks, err := h.spotify.UserTracks(ctx, token, settings.TrackSource, settings.TimeRange, market)
+		if err != nil || len(tracks) == 0 {
+			log.Printf("Keeping saved tracks of player %s: %v", playerID, err)
 			continue
 		}
-
-		allTracks = append(allTracks, tracks...)
+		if err := h.repo.SaveTracks(ctx, roomCode, playerID, tracks); err != nil {
+			log.Println("error saving tracks:", err)
+		}
 	}
-	return allTracks
 }
 
-func tracksFromPlaylist(playlistID string, token string) []model.Track {
-	var allTracks []model.Track
-	offset := 0
-	limit := 100
-
-	client := &http.Client{}
-
-	for {
-		reqURL := fmt.Sprintf("https://api.spotify.com/v1/playlists/%s/tracks?offset=%d&limit=%d", playlistID, offset, limit)
-		req, _ := http.NewRequest("GET", reqURL, nil)
-		req.Header.Set("Authorization", "Bearer "+token)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Println("error fetching playlist:", err)
-			break
-		}
-		defer resp.Body.Close()
-
-		var result struct {
-			Items []struct {
-				Track struct {
-					ID         string `json:"id"`
-					Name       string `json:"name"`
-					DurationMs int    `json:"duration_ms"`
-					Artists    []struct {
-						Name string `json:"name"`
-					} `json:"artists"`
-				} `json:"track"`
-			} `json:"items"`
-			Next string `json:"next"`
-		}
-
-		err = json.NewDecoder(resp.Body).Decode(&result)
+// tracksFromPlayers returns the saved tracks of every player, one list per player.
+func (h *Handler) tracksFromPlayers(ctx context.Context, players []string, roomCode string) [][]model.Track {
+	var allTracks [][]model.Track
+	for _, playerID := range players {
+		tracks, err := h.repo.GetTracks(ctx, roomCode, playerID)
 		if err != nil {
-			log.Println("error decoding playlist response:", err)
-			break
-		}
-
-		for _, item := range result.Items {
-			t := item.Track
-			var artistNames []string
-			for _, a := range t.Artists {
-				artistNames = append(artistNames, a.Name)
-			}
-			allTracks = append(allTracks, model.Track{
-				ID:       t.ID,
-				Name:     t.Name,
-				Artists:  artistNames,
-				Duration: t.DurationMs,
-			})
+			log.Println("no tracks for player:", playerID, err)
+			continue
 		}
 
-		if result.Next == "" {
-			break
-		}
-		offset += limit
-	}
-
-	rand.Shuffle(len(allTracks), func(i, j int) {
-		allTracks[i], allTracks[j] = allTracks[j], allTracks[i]
-	})
-
-	if len(allTracks) > 25 {
-		return allTracks[:25]
+		allTracks = append(allTracks, tracks)
 	}
 	return allTracks
 }
 
-func tracksFromArtist(artistID string, token string) []model.Track {
-	var allTracks []model.Track
-	offset := 0
-	limit := 50
-	client := &http.Client{}
-
-	for {
-		url := fmt.Sprintf("https://api.spotify.com/v1/artists/%s/albums?include_groups=album,single&limit=%d&offset=%d", artistID, limit, offset)
-		req, _ := http.NewRequest("GET", url, nil)
-		req.Header.Set("Authorization", "Bearer "+token)
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Println("Error fetching albums:", err)
-			break
-		}
-		defer resp.Body.Close()
-
-		var albumResp struct {
-			Items []struct {
-				ID string `json:"id"`
-			} `json:"items"`
-			Next string `json:"next"`
+// submissionsByPlayer groups the songs submitted in the lobby by player.
+func submissionsByPlayer(room model.Room) [][]model.Track {
+	byPlayer := make(map[string][]model.Track)
+	for _, track := range room.Submissions {
+		byPlayer[track.SubmittedBy] = append(byPlayer[track.SubmittedBy], track)
+	}
+	var lists [][]model.Track
+	for _, playerID := range room.Players {
+		if songs := byPlayer[playerID]; len(songs) > 0 {
+			lists = append(lists, songs)
 		}
-		json.NewDecoder(resp.Body).Decode(&albumResp)
-
-		for _, album := range albumResp.Items {
-			albumURL := fmt.Sprintf("https://api.spotify.com/v1/albums/%s/tracks", album.ID)
-			albumReq, _ := http.NewRequest("GET", albumURL, nil)
-			albumReq.Header.Set("Authorization", "Bearer "+token)
-			albumResp, err := client.Do(albumReq)
-			if err != nil {
-				continue
-			}
-			defer albumResp.Body.Close()
+	}
+	return lists
+}
 
-			var trackResp struct {
-				Items []struct {
-					ID         string `json:"id"`
-					Name       string `json:"name"`
-					DurationMs int    `json:"duration_ms"`
-					Artists    []struct {
-						Name string `json:"name"`
-					} `json:"artists"`
-				} `json:"items"`
+// maxSourceTracks is how many tracks of a playlist, an artist, albums or a
+// genre are used, picked after the pool filters ran on all fetched tracks.
+const maxSourceTracks = 25
+
+func (h *Handler) tracksFromPlaylist(ctx context.Context, playlistID string, token, market string) []model.Track {
+	tracks := h.cachedTracks(ctx, "playlist", playlistID, market,
+		func(string) (string, error) {
+			return h.spotify.PlaylistSnapshot(ctx, token, playlistID)
+		},
+		func() ([]model.Track, error) {
+			var allTracks []model.Track
+			for track, err := range h.spotify.PlaylistTracks(ctx, token, playlistID, market) {
+				if err != nil {
+					return allTracks, err
+				}
+				allTracks = append(allTracks, track)
+				if len(allTracks) >= maxCandidateTracks {
+					break
+				}
 			}
-			json.NewDecoder(albumResp.Body).Decode(&trackResp)
+			return allTracks, nil
+		})
+	return shuffleTracks(tracks)
+}
 
-			for _, t := range trackResp.Items {
-				var artistNames []string
-				for _, a := range t.Artists {
-					artistNames = append(artistNames, a.Name)
+func (h *Handler) tracksFromArtist(ctx context.Context, artistID string, token, market string) []model.Track {
+	tracks := h.cachedTracks(ctx, "artist", artistID, market,
+		func(etag string) (string, error) {
+			return h.spotify.ArtistAlbumsETag(ctx, token, artistID, market, etag)
+		},
+		func() ([]model.Track, error) {
+			var albumIDs []string
+			for album, err := range h.spotify.ArtistAlbums(ctx, token, artistID, market) {
+				if err != nil {
+					return nil, err
 				}
-				allTracks = append(allTracks, model.Track{
-					ID:       t.ID,
-					Name:     t.Name,
-					Duration: t.DurationMs,
-					Artists:  artistNames,
-				})
+				albumIDs = append(albumIDs, album.ID)
 			}
-		}
+			// Start with random albums, as the fetch stops early.
+			rand.Shuffle(len(albumIDs), func(i, j int) {
+				albumIDs[i], albumIDs[j] = albumIDs[j], albumIDs[i]
+			})
+			return h.fetchAlbums(ctx, albumIDs, token, market), nil
+		})
+	return shuffleTracks(tracks)
+}
 
-		if albumResp.Next == "" {
-			break
-		}
-		offset += limit
+func (h *Handler) tracksFromAlbums(ctx context.Context, albumIDs []string, token, market string) []model.Track {
+	return shuffleTracks(h.fetchAlbums(ctx, albumIDs, token, market))
+}
+
+func (h *Handler) tracksFromGenre(ctx context.Context, query model.GenreQuery, token, market string) []model.Track {
+	tracks, err := h.spotify.GenreTracks(ctx, token, query.Genre, query.FromYear, query.ToYear, market)
+	if err != nil {
+		log.Printf("Error searching genre %q: %v", query.Genre, err)
+		return nil
 	}
+	return shuffleTracks(tracks)
+}
 
-	rand.Shuffle(len(allTracks), func(i, j int) {
-		allTracks[i], allTracks[j] = allTracks[j], allTracks[i]
+// shuffleTracks shuffles the tracks in place and returns them.
+func shuffleTracks(tracks []model.Track) []model.Track {
+	rand.Shuffle(len(tracks), func(i, j int) {
+		tracks[i], tracks[j] = tracks[j], tracks[i]
 	})
-	if len(allTracks) > 25 {
-		return allTracks[:25]
-	}
-	return allTracks
+	return tracks
 }
@@ -0,0 +1,145 @@
+package history
+
+import (
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Store persists finished games and answers queries about a player's past games.
+//
+// Players are identified by a player key: their Spotify ID when it is known,
+// otherwise a model.GuestKey unique to the game. Guests have no history or stats.
+type Store interface {
+	// RecordGame saves a finished game together with its questions, answers and ranking.
+	RecordGame(ctx context.Context, game model.GameRecord) error
+	// PlayerGames returns the most recent games of a player, newest first.
+	PlayerGames(ctx context.Context, playerKey string, limit int) ([]model.PlayerGame, error)
+	// PlayerStats returns the lifetime statistics of a player.
+	PlayerStats(ctx context.Context, playerKey string) (model.LifetimeStats, error)
+	Close() error
+}
+
+// InitSQLite opens the SQLite history database at the path given by the
+// HISTORY_DB environment variable (default "spotiguess.db"). It panics if the
+// database cannot be opened.
+func InitSQLite() *SQLiteStore {
+	path := os.Getenv("HISTORY_DB")
+	if path == "" {
+		path = "spotiguess.db"
+	}
+
+	db, err := OpenSQLite(path)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Handler serves the player history endpoints from a Store.
+type Handler struct {
+	store Store
+}
+
+// NewHandler returns a history Handler reading from the given store.
+func NewHandler(store Store) *Handler {
+	return &Handler{store: store}
+}
+
+// PlayerRouterHandler dispatches /players/{id}/games and /players/{id}/stats.
+func (h *Handler) PlayerRouterHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) != 4 || parts[2] == "" {
+		http.Error(w, "Invalid player route", http.StatusNotFound)
+		return
+	}
+
+	switch parts[3] {
+	case "games":
+		h.PlayerGamesHandler(w, r)
+	case "stats":
+		h.PlayerStatsHandler(w, r)
+	default:
+		http.Error(w, "Invalid player route", http.StatusNotFound)
+	}
+}
+
+// PlayerGamesHandler handles HTTP GET requests to /players/{id}/games.
+//
+// The {id} is the player's Spotify ID; guests are not tracked across games.
+// An optional "limit" query parameter (default 20, max 100) limits the number of games:
+//
+//	GET /players/user123/games?limit=5
+//
+//	Response:
+//	[
+//	  {
+//	    "gameId": "...",
+//	    "roomCode": "ABC123",
+//	    "gameMode": "playlist",
+//	    "finishedAt": "...",
+//	    "rank": 1,
+//	    "players": 4,
+//	    "score": 7450,
+//	    "correct": 8,
+//	    "answered": 10,
+//	    "questions": 10
+//	  }
+//	]
+//
+// Responds with 500 if the history store cannot be queried.
+func (h *Handler) PlayerGamesHandler(w http.ResponseWriter, r *http.Request) {
+	playerKey := strings.Split(r.URL.Path, "/")[2]
+
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(parsed, 100)
+	}
+
+	games, err := h.store.PlayerGames(r.Context(), playerKey, limit)
+	if err != nil {
+		http.Error(w, "Failed to load game history", http.StatusInternalServerError)
+		return
+	}
+	if games == nil {
+		games = []model.PlayerGame{}
+	}
+	json.NewEncoder(w).Encode(games)
+}
+
+// PlayerStatsHandler handles HTTP GET requests to /players/{id}/stats.
+//
+//	Response:
+//	{
+//	  "playerKey": "user123",
+//	  "gamesPlayed": 12,
+//	  "wins": 5,
+//	  "totalScore": 61200,
+//	  "bestScore": 8120,
+//	  "avgScore": 5100,
+//	  "correct": 84,
+//	  "answered": 110,
+//	  "accuracy": 0.7,
+//	  "avgResponseMs": 3410
+//	}
+//
+// A player without recorded games gets zeroed statistics.
+func (h *Handler) PlayerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	playerKey := strings.Split(r.URL.Path, "/")[2]
+
+	stats, err := h.store.PlayerStats(r.Context(), playerKey)
+	if err != nil {
+		http.Error(w, "Failed to load player stats", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(stats)
+}
@@ -0,0 +1,206 @@
+package history
+
+import (
+	"backend/internal/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS games (
+	id TEXT PRIMARY KEY,
+	room_code TEXT NOT NULL,
+	host_id TEXT NOT NULL,
+	game_mode TEXT NOT NULL,
+	query_data TEXT NOT NULL,
+	finished_at INTEGER NOT NULL,
+	question_count INTEGER NOT NULL,
+	player_count INTEGER NOT NULL,
+	questions TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS game_players (
+	game_id TEXT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+	player_key TEXT NOT NULL,
+	player_id TEXT NOT NULL,
+	spotify_id TEXT NOT NULL,
+	rank INTEGER NOT NULL,
+	score INTEGER NOT NULL,
+	correct INTEGER NOT NULL,
+	answered INTEGER NOT NULL,
+	avg_response_ms INTEGER NOT NULL,
+	PRIMARY KEY (game_id, player_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_game_players_key ON game_players(player_key);
+
+CREATE TABLE IF NOT EXISTS answers (
+	game_id TEXT NOT NULL REFERENCES games(id) ON DELETE CASCADE,
+	question_id TEXT NOT NULL,
+	player_id TEXT NOT NULL,
+	selected TEXT NOT NULL,
+	correct INTEGER NOT NULL,
+	response_ms INTEGER NOT NULL,
+	points INTEGER NOT NULL,
+	PRIMARY KEY (game_id, question_id, player_id)
+);
+`
+
+// SQLiteStore is a Store backed by a local SQLite database.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (or creates) the SQLite database at path and applies the schema.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// RecordGame inserts the game, its ranking and every answer in a single transaction.
+func (s *SQLiteStore) RecordGame(ctx context.Context, game model.GameRecord) error {
+	questions, err := json.Marshal(game.Questions)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO games (id, room_code, host_id, game_mode, query_data, finished_at, question_count, player_count, questions)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		game.ID, game.RoomCode, game.HostID, game.GameMode, game.QueryData,
+		game.FinishedAt.Unix(), len(game.Questions), len(game.Ranking), string(questions),
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range game.Ranking {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO game_players (game_id, player_key, player_id, spotify_id, rank, score, correct, answered, avg_response_ms)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			game.ID, entry.PlayerKey, entry.PlayerID, entry.SpotifyID, entry.Rank,
+			entry.Score, entry.Correct, entry.Answered, entry.AvgResponseMs,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for questionID, answers := range game.Answers {
+		for _, answer := range answers {
+			_, err = tx.ExecContext(ctx,
+				`INSERT INTO answers (game_id, question_id, player_id, selected, correct, response_ms, points)
+				 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				game.ID, questionID, answer.PlayerID, answer.Selected, answer.Correct,
+				answer.ResponseMs, answer.Points,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PlayerGames returns up to limit games of the player, newest first. Guests
+// have no history.
+func (s *SQLiteStore) PlayerGames(ctx context.Context, playerKey string, limit int) ([]model.PlayerGame, error) {
+	if model.IsGuestKey(playerKey) {
+		return nil, nil
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT g.id, g.room_code, g.game_mode, g.finished_at, g.player_count, g.question_count,
+		        p.player_id, p.rank, p.score, p.correct, p.answered
+		 FROM game_players p JOIN games g ON g.id = p.game_id
+		 WHERE p.player_key = ?
+		 ORDER BY g.finished_at DESC
+		 LIMIT ?`,
+		playerKey, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var games []model.PlayerGame
+	for rows.Next() {
+		var game model.PlayerGame
+		var finishedAt int64
+		err := rows.Scan(
+			&game.GameID, &game.RoomCode, &game.GameMode, &finishedAt, &game.Players, &game.Questions,
+			&game.PlayerID, &game.Rank, &game.Score, &game.Correct, &game.Answered,
+		)
+		if err != nil {
+			return nil, err
+		}
+		game.FinishedAt = time.Unix(finishedAt, 0)
+		games = append(games, game)
+	}
+	return games, rows.Err()
+}
+
+// PlayerStats aggregates all recorded games of the player. Guests get zeroed
+// statistics.
+func (s *SQLiteStore) PlayerStats(ctx context.Context, playerKey string) (model.LifetimeStats, error) {
+	stats := model.LifetimeStats{PlayerKey: playerKey}
+	if model.IsGuestKey(playerKey) {
+		return stats, nil
+	}
+
+	var questions, responseTotal int64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+		        COALESCE(SUM(CASE WHEN p.rank = 1 THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(p.score), 0),
+		        COALESCE(MAX(p.score), 0),
+		        COALESCE(SUM(p.correct), 0),
+		        COALESCE(SUM(p.answered), 0),
+		        COALESCE(SUM(g.question_count), 0),
+		        COALESCE(SUM(p.avg_response_ms * p.answered), 0)
+		 FROM game_players p JOIN games g ON g.id = p.game_id
+		 WHERE p.player_key = ?`,
+		playerKey,
+	).Scan(
+		&stats.GamesPlayed, &stats.Wins, &stats.TotalScore, &stats.BestScore,
+		&stats.Correct, &stats.Answered, &questions, &responseTotal,
+	)
+	if err != nil {
+		return stats, err
+	}
+
+	if stats.GamesPlayed > 0 {
+		stats.AvgScore = float64(stats.TotalScore) / float64(stats.GamesPlayed)
+	}
+	if questions > 0 {
+		stats.Accuracy = float64(stats.Correct) / float64(questions)
+	}
+	if stats.Answered > 0 {
+		stats.AvgResponseMs = responseTotal / int64(stats.Answered)
+	}
+	return stats, nil
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,47 @@
+package history
+
+import (
+	"backend/internal/model"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordGameGuests(t *testing.T) {
+	ctx := context.Background()
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// A guest named like a Spotify user, in two games.
+	for i, gameID := range []string{"ABC123-1", "ABC123-2"} {
+		err := store.RecordGame(ctx, model.GameRecord{
+			ID:         gameID,
+			RoomCode:   "ABC123",
+			FinishedAt: time.Unix(int64(1000+i), 0),
+			Ranking: []model.RankingEntry{
+				{PlayerKey: "kasia", PlayerID: "kasia-spotify", SpotifyID: "kasia", Rank: 1, Score: 900},
+				{PlayerKey: model.GuestKey(gameID, 1), PlayerID: "kasia", Rank: 2, Score: 500},
+			},
+		})
+		if err != nil {
+			t.Fatalf("RecordGame %s: %v", gameID, err)
+		}
+	}
+
+	stats, err := store.PlayerStats(ctx, "kasia")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.GamesPlayed != 2 || stats.TotalScore != 1800 {
+		t.Fatalf("stats of the Spotify user = %+v, want only their own games", stats)
+	}
+	stats, _ = store.PlayerStats(ctx, model.GuestKey("ABC123-1", 1))
+	games, _ := store.PlayerGames(ctx, model.GuestKey("ABC123-1", 1), 10)
+	if stats.GamesPlayed != 0 || len(games) != 0 {
+		t.Fatalf("guest stats = %+v, games = %v; want none", stats, games)
+	}
+}
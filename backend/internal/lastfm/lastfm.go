@@ -2,6 +2,7 @@ package lastfm
 
 import (
 	"backend/internal/model"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -19,6 +20,7 @@ type similarResponse struct {
 }
 
 // FetchSimilar queries the Last.fm API for similar tracks based on the given track.
+// The request is cancelled with ctx.
 //
 // It uses the Last.fm `track.getsimilar` endpoint to fetch up to 3 related track names,
 // which can be used as "fake answers" for a quiz question.
@@ -52,7 +54,7 @@ type similarResponse struct {
 // Note:
 // - The function assumes that `track.Artists` is non-empty.
 // - If the API key is missing or Last.fm returns an error, the function will fail.
-func FetchSimilar(track model.Track) ([]string, error) {
+func FetchSimilar(ctx context.Context, track model.Track) ([]string, error) {
 
 	api_key := os.Getenv("LASTFM_API_KEY")
 	endpoint := fmt.Sprintf(
@@ -62,7 +64,7 @@ func FetchSimilar(track model.Track) ([]string, error) {
 		api_key,
 	)
 
-	req, err := http.NewRequest("GET", endpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,55 @@
+package lobby
+
+import "sync"
+
+// feedBuffer is how many events a subscriber may fall behind before it is dropped.
+const feedBuffer = 32
+
+// Event is a change of the lobby list, sent to feed subscribers.
+type Event struct {
+	Type string
+	Data any
+}
+
+// Feed fans out lobby list changes to the connected browsers.
+type Feed struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// NewFeed returns a Feed without subscribers.
+func NewFeed() *Feed {
+	return &Feed{subscribers: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every following event and a function
+// to unsubscribe. The channel is closed if the subscriber falls behind; the
+// client should reconnect and start over from a fresh list.
+func (f *Feed) Subscribe() (<-chan Event, func()) {
+	events := make(chan Event, feedBuffer)
+	f.mu.Lock()
+	f.subscribers[events] = struct{}{}
+	f.mu.Unlock()
+
+	return events, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.subscribers[events]; ok {
+			delete(f.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+func (f *Feed) publish(event Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for events := range f.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(f.subscribers, events)
+			close(events)
+		}
+	}
+}
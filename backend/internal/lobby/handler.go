@@ -0,0 +1,147 @@
+package lobby
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"backend/internal/store"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// keepAliveInterval is how often the feed sends a comment line so proxies do
+// not close idle connections.
+const keepAliveInterval = 25 * time.Second
+
+// Handler serves the lobby browser.
+type Handler struct {
+	repo store.Repository
+	feed *Feed
+}
+
+// NewHandler returns a Handler listing rooms from repo and streaming feed.
+func NewHandler(repo store.Repository, feed *Feed) *Handler {
+	return &Handler{repo: repo, feed: feed}
+}
+
+// publicRooms returns the listed rooms: open lobbies first, then by number
+// of players.
+func (h *Handler) publicRooms(ctx context.Context) ([]model.PublicRoom, error) {
+	rooms, err := h.repo.ListPublicRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		iLobby, jLobby := rooms[i].State == model.StateLobby, rooms[j].State == model.StateLobby
+		if iLobby != jLobby {
+			return iLobby
+		}
+		if rooms[i].Players != rooms[j].Players {
+			return rooms[i].Players > rooms[j].Players
+		}
+		return rooms[i].Code < rooms[j].Code
+	})
+	return rooms, nil
+}
+
+// ListHandler handles HTTP GET requests to /lobbies.
+//
+// It returns the public rooms that can be found in the lobby browser. Rooms
+// are listed while they are public, not locked and not closed; the host sets
+// this with "public" and "title" in the room settings:
+//
+//	Response:
+//	{
+//	  "rooms": [
+//	    {
+//	      "roomCode": "ABC123",
+//	      "title": "Friday 90s night",
+//	      "gameMode": "playlist",
+//	      "state": "lobby",
+//	      "hostName": "DJ Kasia",
+//	      "players": 3,
+//	      "maxPlayers": 8,
+//	      "hasPassword": false,
+//	      "lateJoin": true,
+//	      "updatedAt": "2025-06-01T20:00:00Z"
+//	    }
+//	  ]
+//	}
+//
+// Rooms in the lobby come first, then rooms with more players.
+func (h *Handler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	rooms, err := h.publicRooms(r.Context())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"rooms": rooms})
+}
+
+// FeedHandler handles HTTP GET requests to /lobbies/feed.
+//
+// It streams the lobby list as server-sent events. The first event is the
+// full list, followed by a change whenever a public room is listed, changes,
+// is unlisted or expires:
+//
+//	event: snapshot
+//	data: [{"roomCode":"ABC123","title":"Friday 90s night",...}]
+//
+//	event: room-updated
+//	data: {"roomCode":"ABC123","title":"Friday 90s night","players":4,...}
+//
+//	event: room-removed
+//	data: {"roomCode":"ABC123"}
+//
+// If the client cannot keep up the stream ends; EventSource reconnects on
+// its own and receives a new snapshot.
+func (h *Handler) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := h.feed.Subscribe()
+	defer unsubscribe()
+
+	rooms, err := h.publicRooms(r.Context())
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	writeEvent(w, Event{Type: "snapshot", Data: rooms})
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event Event) {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
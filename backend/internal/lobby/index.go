@@ -0,0 +1,142 @@
+// Package lobby keeps the index of public rooms shown in the lobby browser and
+// streams changes to it.
+package lobby
+
+import (
+	"backend/internal/model"
+	"backend/internal/store"
+	"context"
+	"log"
+	"time"
+)
+
+// Repository wraps a store.Repository and keeps the public room index in sync
+// with every room that is created, updated or deleted through it. Changes to
+// the index are published on the Feed.
+type Repository struct {
+	store.Repository
+	feed *Feed
+}
+
+// NewRepository returns repo with lobby indexing. All handlers should share
+// the returned Repository so no room change bypasses the index.
+func NewRepository(repo store.Repository, feed *Feed) *Repository {
+	return &Repository{Repository: repo, feed: feed}
+}
+
+// listing returns the lobby browser entry of the room, or false if the room
+// should not be listed: it is private, locked or closed.
+func listing(room model.Room) (model.PublicRoom, bool) {
+	settings := room.RoomSettings
+	if !settings.Public || settings.Locked || room.State() == model.StateClosed {
+		return model.PublicRoom{}, false
+	}
+
+	host := room.Profile(room.HostId).DisplayName
+	title := settings.Title
+	if title == "" && host != "" {
+		title = host + "'s room"
+	} else if title == "" {
+		title = "Room " + room.Code
+	}
+	return model.PublicRoom{
+		Code:        room.Code,
+		Title:       title,
+		GameMode:    room.GameMode,
+		State:       room.State(),
+		HostName:    host,
+		Players:     len(room.Players),
+		MaxPlayers:  settings.Capacity(),
+		HasPassword: settings.HasPassword,
+		LateJoin:    settings.LateJoin,
+		UpdatedAt:   time.Now(),
+	}, true
+}
+
+func (r *Repository) CreateRoom(ctx context.Context, room model.Room) error {
+	if err := r.Repository.CreateRoom(ctx, room); err != nil {
+		return err
+	}
+	r.sync(ctx, room)
+	return nil
+}
+
+func (r *Repository) UpdateRoom(ctx context.Context, code string, update store.RoomUpdate) (model.Room, error) {
+	room, err := r.Repository.UpdateRoom(ctx, code, update)
+	if err != nil {
+		return room, err
+	}
+	r.sync(ctx, room)
+	return room, nil
+}
+
+func (r *Repository) DeleteRoom(ctx context.Context, code string) error {
+	if err := r.Repository.DeleteRoom(ctx, code); err != nil {
+		return err
+	}
+	r.remove(ctx, code)
+	return nil
+}
+
+// sync lists or unlists the room after a change. Index failures are logged
+// and never fail the room change itself.
+func (r *Repository) sync(ctx context.Context, room model.Room) {
+	entry, ok := listing(room)
+	if !ok {
+		r.remove(ctx, room.Code)
+		return
+	}
+	if err := r.SavePublicRoom(ctx, entry); err != nil {
+		log.Printf("Failed to list public room %s: %v", room.Code, err)
+		return
+	}
+	r.feed.publish(Event{Type: "room-updated", Data: entry})
+}
+
+// ListPublicRooms returns the listed rooms. Entries not updated within
+// RoomTTL belong to rooms that have expired; they are unlisted, which is
+// published as "room-removed".
+func (r *Repository) ListPublicRooms(ctx context.Context) ([]model.PublicRoom, error) {
+	rooms, err := r.Repository.ListPublicRooms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-store.RoomTTL)
+	live := rooms[:0]
+	for _, room := range rooms {
+		if room.UpdatedAt.Before(cutoff) {
+			r.remove(ctx, room.Code)
+			continue
+		}
+		live = append(live, room)
+	}
+	return live, nil
+}
+
+// PruneEvery unlists expired rooms every interval until ctx is done, so feed
+// subscribers see them go even when nobody loads the list.
+func (r *Repository) PruneEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.ListPublicRooms(ctx); err != nil {
+				log.Println("Failed to prune public rooms:", err)
+			}
+		}
+	}
+}
+
+func (r *Repository) remove(ctx context.Context, code string) {
+	removed, err := r.DeletePublicRoom(ctx, code)
+	if err != nil {
+		log.Printf("Failed to unlist room %s: %v", code, err)
+		return
+	}
+	if removed {
+		r.feed.publish(Event{Type: "room-removed", Data: map[string]string{"roomCode": code}})
+	}
+}
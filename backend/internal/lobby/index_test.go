@@ -0,0 +1,40 @@
+package lobby
+
+import (
+	"backend/internal/model"
+	"backend/internal/store"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestListPublicRoomsUnlistsExpiredRooms(t *testing.T) {
+	ctx := context.Background()
+	feed := NewFeed()
+	repo := NewRepository(store.NewMemoryRepository(), feed)
+	repo.SavePublicRoom(ctx, model.PublicRoom{Code: "LIVE11", UpdatedAt: time.Now()})
+	repo.SavePublicRoom(ctx, model.PublicRoom{Code: "GONE22", UpdatedAt: time.Now().Add(-store.RoomTTL - time.Minute)})
+
+	events, unsubscribe := feed.Subscribe()
+	defer unsubscribe()
+	rooms, err := repo.ListPublicRooms(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms) != 1 || rooms[0].Code != "LIVE11" {
+		t.Fatalf("rooms = %+v, want only the live room", rooms)
+	}
+
+	select {
+	case event := <-events:
+		data, _ := event.Data.(map[string]string)
+		if event.Type != "room-removed" || data["roomCode"] != "GONE22" {
+			t.Fatalf("event = %+v, want the expired room removed", event)
+		}
+	default:
+		t.Fatal("no event for the expired room")
+	}
+	if removed, _ := repo.DeletePublicRoom(ctx, "GONE22"); removed {
+		t.Fatal("the expired room is still indexed")
+	}
+}
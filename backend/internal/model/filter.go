@@ -0,0 +1,42 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// VersionKeywords mark alternate versions of a song in its title. They are
+// filtered out of wrong answers and, with ExcludeVersions, of the track pool.
+var VersionKeywords = []string{"acoustic", "remix", "live", "instrumental", "karaoke", "cover"}
+
+// IsAlternateVersion reports whether the title marks the track as an
+// alternate version: one of the VersionKeywords appears as a word in a
+// parenthesised or bracketed part or after " - ", like "Song - Live" or
+// "Song (Karaoke Version)". Keywords in the song's name itself, as in
+// "Live Forever" or "Cover Me", do not count.
+func IsAlternateVersion(title string) bool {
+	words := strings.FieldsFunc(versionSuffix(strings.ToLower(title)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		for _, keyword := range VersionKeywords {
+			if word == keyword {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// versionSuffix returns the parts of the title after the song's name: from
+// the first "(" or "[", and after the first " - ".
+func versionSuffix(title string) string {
+	var suffix string
+	if i := strings.IndexAny(title, "(["); i > 0 {
+		title, suffix = title[:i], title[i:]
+	}
+	if i := strings.Index(title, " - "); i > 0 {
+		suffix = title[i:] + suffix
+	}
+	return suffix
+}
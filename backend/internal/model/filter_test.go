@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestIsAlternateVersion(t *testing.T) {
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Live Forever", false},
+		{"Live and Let Die", false},
+		{"Cover Me", false},
+		{"Live Forever - Remastered", false},
+		{"Delivery (Radio Edit)", false},
+		{"Song - Live", true},
+		{"Song (Karaoke Version)", true},
+		{"Song [Acoustic]", true},
+		{"Live and Let Die - Live at Wembley", true},
+		{"Cover Me (Remix) - 2011 Remaster", true},
+	}
+	for _, tt := range tests {
+		if got := IsAlternateVersion(tt.title); got != tt.want {
+			t.Errorf("IsAlternateVersion(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxAlbums is how many albums an "album" game may use.
+	MaxAlbums = 10
+	// MinPoolSize is the smallest track pool a game starts with. Games with
+	// fewer questions than MinPoolSize need as many tracks as questions.
+	MinPoolSize = 5
+	// minYear is the earliest year of a "genre" game's year range.
+	minYear = 1900
+)
+
+// GenreQuery is the query of a "genre" game: a Spotify genre and an optional
+// range of release years. Zero years mean no year filter.
+type GenreQuery struct {
+	Genre    string
+	FromYear int
+	ToYear   int
+}
+
+// ParseGenreQuery parses the "tracksData" of a "genre" game. It is the genre,
+// optionally followed by a colon and a year or a year range:
+//
+//	rock
+//	hip hop:2015
+//	indie rock:1990-1999
+func ParseGenreQuery(query string) (GenreQuery, error) {
+	genre, years, hasYears := strings.Cut(query, ":")
+	q := GenreQuery{Genre: strings.Join(strings.Fields(genre), " ")}
+	if q.Genre == "" {
+		return GenreQuery{}, fmt.Errorf("genre is missing")
+	}
+	if !hasYears {
+		return q, nil
+	}
+
+	from, to, isRange := strings.Cut(strings.TrimSpace(years), "-")
+	var err error
+	if q.FromYear, err = strconv.Atoi(strings.TrimSpace(from)); err != nil {
+		return GenreQuery{}, fmt.Errorf("invalid year %q", from)
+	}
+	q.ToYear = q.FromYear
+	if isRange {
+		if q.ToYear, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+			return GenreQuery{}, fmt.Errorf("invalid year %q", to)
+		}
+	}
+	maxYear := time.Now().Year()
+	if q.FromYear < minYear || q.ToYear > maxYear || q.FromYear > q.ToYear {
+		return GenreQuery{}, fmt.Errorf("years must be between %d and %d", minYear, maxYear)
+	}
+	return q, nil
+}
+
+// AlbumIDs splits the comma-separated "tracksData" of an "album" game.
+func AlbumIDs(query string) []string {
+	var ids []string
+	for _, id := range strings.Split(query, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ValidateQuery checks the "tracksData" of a game mode: a playlist or artist
+// ID, 1 to MaxAlbums comma-separated album IDs or a genre query. The
+// "players" mode takes no query.
+func ValidateQuery(mode, query string) error {
+	switch mode {
+	case "playlist", "artist":
+		if strings.TrimSpace(query) == "" {
+			return fmt.Errorf("%s ID is missing", mode)
+		}
+	case "album":
+		ids := AlbumIDs(query)
+		if len(ids) == 0 || len(ids) > MaxAlbums {
+			return fmt.Errorf("choose between 1 and %d albums", MaxAlbums)
+		}
+		for _, id := range ids {
+			if strings.ContainsFunc(id, func(r rune) bool {
+				return !('0' <= r && r <= '9' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z')
+			}) {
+				return fmt.Errorf("invalid album ID %q", id)
+			}
+		}
+	case "genre":
+		_, err := ParseGenreQuery(query)
+		return err
+	}
+	return nil
+}
+
+// MaxSources is how many sources a "mixed" game may combine.
+const MaxSources = 5
+
+// PoolSource is one source of a "mixed" game's track pool: a game mode with
+// its "tracksData" (empty for "players") and a weight from 1 to 10. A source
+// with weight 2 gets twice the share of the pool of a source with weight 1.
+type PoolSource struct {
+	Mode   string `json:"mode"`
+	ID     string `json:"id,omitempty"`
+	Weight *int   `json:"weight,omitempty"`
+}
+
+// EffectiveWeight is the weight, defaulting to 1 when it is not set.
+func (s PoolSource) EffectiveWeight() int {
+	if s.Weight == nil {
+		return 1
+	}
+	return *s.Weight
+}
+
+// ValidateSources checks the sources of a "mixed" game.
+func ValidateSources(sources []PoolSource) error {
+	if len(sources) == 0 || len(sources) > MaxSources {
+		return fmt.Errorf("choose between 1 and %d sources", MaxSources)
+	}
+	for _, source := range sources {
+		if !ValidGameMode(source.Mode) {
+			return fmt.Errorf("unsupported source mode %q", source.Mode)
+		}
+		if source.Weight != nil && (*source.Weight < 1 || *source.Weight > 10) {
+			return fmt.Errorf("weight must be between 1 and 10")
+		}
+		if err := ValidateQuery(source.Mode, source.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MinTracks is how many tracks a game with questionCount questions needs at least.
+func MinTracks(questionCount int) int {
+	return min(MinPoolSize, questionCount)
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateSourcesWeight(t *testing.T) {
+	tests := []struct {
+		body string
+		ok   bool
+	}{
+		{`[{"mode": "players"}]`, true},
+		{`[{"mode": "players", "weight": 1}]`, true},
+		{`[{"mode": "players", "weight": 10}]`, true},
+		{`[{"mode": "players", "weight": 0}]`, false},
+		{`[{"mode": "players", "weight": -1}]`, false},
+		{`[{"mode": "players", "weight": 11}]`, false},
+	}
+	for _, tt := range tests {
+		var sources []PoolSource
+		if err := json.Unmarshal([]byte(tt.body), &sources); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateSources(sources); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want ok = %v", tt.body, err, tt.ok)
+		}
+	}
+
+	var sources []PoolSource
+	json.Unmarshal([]byte(`[{"mode": "players"}, {"mode": "players", "weight": 3}]`), &sources)
+	if sources[0].EffectiveWeight() != 1 || sources[1].EffectiveWeight() != 3 {
+		t.Fatalf("weights = %d, %d; want 1, 3", sources[0].EffectiveWeight(), sources[1].EffectiveWeight())
+	}
+}
@@ -0,0 +1,211 @@
+package model
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// GameSettings are the per-game options chosen by the host.
+// Zero values mean "use the default".
+type GameSettings struct {
+	QuestionCount int `json:"questionCount,omitempty"`
+	AnswerSeconds int `json:"answerSeconds,omitempty"`
+	RevealSeconds int `json:"revealSeconds,omitempty"`
+	// CountdownSeconds is the countdown shown before the first question.
+	CountdownSeconds int `json:"countdownSeconds,omitempty"`
+	// TrackSource picks the players' tracks in "players" mode: "recent"
+	// (recently played), "top" (top tracks over TimeRange) or "liked".
+	TrackSource string `json:"trackSource,omitempty"`
+	// TimeRange is the Spotify time range of the top tracks: "short_term"
+	// (about 4 weeks), "medium_term" (6 months) or "long_term" (about a year).
+	TimeRange string `json:"timeRange,omitempty"`
+	// AvoidRecentGames skips tracks the room heard in its last N games, as
+	// long as enough other tracks are left. 0 turns it off.
+	AvoidRecentGames int `json:"avoidRecentGames,omitempty"`
+	// ExcludeExplicit leaves tracks marked explicit out of the pool.
+	ExcludeExplicit bool `json:"excludeExplicit,omitempty"`
+	// MinDurationSeconds and MaxDurationSeconds limit the track length.
+	// 0 means no limit.
+	MinDurationSeconds int `json:"minDurationSeconds,omitempty"`
+	MaxDurationSeconds int `json:"maxDurationSeconds,omitempty"`
+	// ExcludeVersions leaves live versions, remixes, covers and the other
+	// VersionKeywords out of the pool.
+	ExcludeVersions bool `json:"excludeVersions,omitempty"`
+}
+
+// MaxAvoidRecentGames is the largest AvoidRecentGames; rooms remember the
+// tracks of this many games.
+const MaxAvoidRecentGames = 10
+
+const (
+	DefaultQuestionCount    = 10
+	DefaultAnswerSeconds    = 15
+	DefaultRevealSeconds    = 5
+	DefaultCountdownSeconds = 5
+)
+
+// Track sources of the "players" mode.
+const (
+	SourceRecent = "recent"
+	SourceTop    = "top"
+	SourceLiked  = "liked"
+)
+
+// Time ranges of the top tracks.
+const (
+	RangeShort  = "short_term"
+	RangeMedium = "medium_term"
+	RangeLong   = "long_term"
+)
+
+// WithDefaults returns a copy of the settings with zero values replaced by defaults.
+func (s GameSettings) WithDefaults() GameSettings {
+	if s.QuestionCount == 0 {
+		s.QuestionCount = DefaultQuestionCount
+	}
+	if s.AnswerSeconds == 0 {
+		s.AnswerSeconds = DefaultAnswerSeconds
+	}
+	if s.RevealSeconds == 0 {
+		s.RevealSeconds = DefaultRevealSeconds
+	}
+	if s.CountdownSeconds == 0 {
+		s.CountdownSeconds = DefaultCountdownSeconds
+	}
+	if s.TrackSource == "" {
+		s.TrackSource = SourceRecent
+	}
+	if s.TimeRange == "" {
+		s.TimeRange = RangeMedium
+	}
+	return s
+}
+
+// Validate checks that every non-zero setting is within its allowed range.
+func (s GameSettings) Validate() error {
+	if s.QuestionCount != 0 && (s.QuestionCount < 1 || s.QuestionCount > 50) {
+		return fmt.Errorf("questionCount must be between 1 and 50")
+	}
+	if s.AnswerSeconds != 0 && (s.AnswerSeconds < 5 || s.AnswerSeconds > 60) {
+		return fmt.Errorf("answerSeconds must be between 5 and 60")
+	}
+	if s.RevealSeconds != 0 && (s.RevealSeconds < 2 || s.RevealSeconds > 30) {
+		return fmt.Errorf("revealSeconds must be between 2 and 30")
+	}
+	if s.CountdownSeconds != 0 && (s.CountdownSeconds < 3 || s.CountdownSeconds > 15) {
+		return fmt.Errorf("countdownSeconds must be between 3 and 15")
+	}
+	switch s.TrackSource {
+	case "", SourceRecent, SourceTop, SourceLiked:
+	default:
+		return fmt.Errorf("trackSource must be %q, %q or %q", SourceRecent, SourceTop, SourceLiked)
+	}
+	switch s.TimeRange {
+	case "", RangeShort, RangeMedium, RangeLong:
+	default:
+		return fmt.Errorf("timeRange must be %q, %q or %q", RangeShort, RangeMedium, RangeLong)
+	}
+	if s.AvoidRecentGames < 0 || s.AvoidRecentGames > MaxAvoidRecentGames {
+		return fmt.Errorf("avoidRecentGames must be between 0 and %d", MaxAvoidRecentGames)
+	}
+	if s.MinDurationSeconds < 0 || s.MinDurationSeconds > 600 {
+		return fmt.Errorf("minDurationSeconds must be between 0 and 600")
+	}
+	if s.MaxDurationSeconds != 0 && (s.MaxDurationSeconds < 30 || s.MaxDurationSeconds > 3600) {
+		return fmt.Errorf("maxDurationSeconds must be between 30 and 3600")
+	}
+	if s.MaxDurationSeconds != 0 && s.MinDurationSeconds > s.MaxDurationSeconds {
+		return fmt.Errorf("minDurationSeconds must not exceed maxDurationSeconds")
+	}
+	return nil
+}
+
+// RoomSettings control who may join a room. They are chosen when the room is
+// created and can be changed by the host while the room is in the lobby.
+type RoomSettings struct {
+	// MaxPlayers is the room capacity. Zero means DefaultMaxPlayers.
+	MaxPlayers int `json:"maxPlayers"`
+	// HasPassword reports whether joining requires a password. The password
+	// itself is only kept as a hash on the room.
+	HasPassword bool `json:"hasPassword"`
+	// LateJoin lets players join a running game; they start with zero points.
+	LateJoin bool `json:"lateJoin"`
+	// Locked rejects every new player, regardless of the other settings.
+	Locked bool `json:"locked"`
+	// SongsPerPlayer is how many songs each player may submit in the lobby.
+	// Zero means DefaultSongsPerPlayer.
+	SongsPerPlayer int `json:"songsPerPlayer,omitempty"`
+	// Public lists the room in the lobby browser under Title.
+	Public bool   `json:"public"`
+	Title  string `json:"title,omitempty"`
+}
+
+const (
+	DefaultMaxPlayers = 8
+	MaxPlayersLimit   = 20
+	MaxTitleLength    = 40
+
+	DefaultSongsPerPlayer = 3
+	MaxSongsPerPlayer     = 10
+)
+
+// Capacity returns the maximum number of players, applying the default.
+func (s RoomSettings) Capacity() int {
+	if s.MaxPlayers == 0 {
+		return DefaultMaxPlayers
+	}
+	return s.MaxPlayers
+}
+
+// SongLimit returns how many songs each player may submit, applying the default.
+func (s RoomSettings) SongLimit() int {
+	if s.SongsPerPlayer == 0 {
+		return DefaultSongsPerPlayer
+	}
+	return s.SongsPerPlayer
+}
+
+// RoomSettingsUpdate is a partial change of the room settings. Nil fields are
+// left unchanged; an empty Password removes the password.
+type RoomSettingsUpdate struct {
+	MaxPlayers *int    `json:"maxPlayers,omitempty"`
+	Password   *string `json:"password,omitempty"`
+	LateJoin   *bool   `json:"lateJoin,omitempty"`
+	Locked     *bool   `json:"locked,omitempty"`
+	// SongsPerPlayer limits the songs each player may submit in the lobby.
+	SongsPerPlayer *int    `json:"songsPerPlayer,omitempty"`
+	Public         *bool   `json:"public,omitempty"`
+	Title          *string `json:"title,omitempty"`
+	// GameMode announces the planned mode in the lobby browser. It is used
+	// by /start-game when the request does not name a mode.
+	GameMode *string `json:"gameMode,omitempty"`
+}
+
+// ValidGameMode reports whether mode is one of the supported game modes.
+func ValidGameMode(mode string) bool {
+	switch mode {
+	case "players", "playlist", "artist", "album", "genre":
+		return true
+	}
+	return false
+}
+
+// Validate checks the requested capacity, song limit, password, title and game mode.
+func (u RoomSettingsUpdate) Validate() error {
+	if u.MaxPlayers != nil && (*u.MaxPlayers < 1 || *u.MaxPlayers > MaxPlayersLimit) {
+		return fmt.Errorf("maxPlayers must be between 1 and %d", MaxPlayersLimit)
+	}
+	if u.SongsPerPlayer != nil && (*u.SongsPerPlayer < 1 || *u.SongsPerPlayer > MaxSongsPerPlayer) {
+		return fmt.Errorf("songsPerPlayer must be between 1 and %d", MaxSongsPerPlayer)
+	}
+	if u.Password != nil && len(*u.Password) > 72 {
+		return fmt.Errorf("password must be at most 72 bytes")
+	}
+	if u.Title != nil && utf8.RuneCountInString(*u.Title) > MaxTitleLength {
+		return fmt.Errorf("title must be at most %d characters", MaxTitleLength)
+	}
+	if u.GameMode != nil && *u.GameMode != "" && !ValidGameMode(*u.GameMode) {
+		return fmt.Errorf("unsupported game mode %q", *u.GameMode)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package model
+
+import "fmt"
+
+// GameState is the lifecycle phase of a room.
+type GameState string
+
+const (
+	// StateLobby: players are joining and the host picks the game mode.
+	StateLobby GameState = "lobby"
+	// StateGenerating: tracks are being fetched and questions generated.
+	StateGenerating GameState = "generating"
+	// StateInRound: a question is being played and answers are accepted.
+	StateInRound GameState = "in-round"
+	// StateReveal: the round is over and the scoreboard is shown.
+	StateReveal GameState = "reveal"
+	// StateFinished: all questions were played and the final results are out.
+	// The host may start a rematch (back to StateLobby) or close the room.
+	StateFinished GameState = "finished"
+	// StateClosed: the room is shut down and its data is being removed.
+	StateClosed GameState = "closed"
+)
+
+// transitions lists the states each state may move to.
+var transitions = map[GameState][]GameState{
+	StateLobby:      {StateGenerating, StateClosed},
+	StateGenerating: {StateInRound, StateLobby, StateClosed},
+	StateInRound:    {StateReveal, StateClosed},
+	StateReveal:     {StateInRound, StateFinished, StateClosed},
+	StateFinished:   {StateLobby, StateClosed},
+	StateClosed:     {},
+}
+
+// TransitionError is returned when a room is asked to move to a state that is
+// not reachable from its current state.
+type TransitionError struct {
+	From GameState
+	To   GameState
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move room from %s to %s", e.From, e.To)
+}
+
+// CanTransition reports whether a room in state from may move to state to.
+func CanTransition(from, to GameState) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns the room's current state. Rooms created before the state
+// machine existed ("waiting" or empty) are reported as StateLobby.
+func (r *Room) State() GameState {
+	if r.GameState == "" || r.GameState == "waiting" {
+		return StateLobby
+	}
+	return r.GameState
+}
+
+// Transition moves the room to the given state, or returns a *TransitionError
+// if the move is not allowed.
+func (r *Room) Transition(to GameState) error {
+	from := r.State()
+	if !CanTransition(from, to) {
+		return &TransitionError{From: from, To: to}
+	}
+	r.GameState = to
+	return nil
+}
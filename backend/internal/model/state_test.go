@@ -0,0 +1,53 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoomTransition(t *testing.T) {
+	tests := []struct {
+		from, to GameState
+		ok       bool
+	}{
+		{StateLobby, StateGenerating, true},
+		{StateGenerating, StateInRound, true},
+		{StateGenerating, StateLobby, true},
+		{StateInRound, StateReveal, true},
+		{StateReveal, StateInRound, true},
+		{StateReveal, StateFinished, true},
+		{StateFinished, StateLobby, true},
+		{StateLobby, StateClosed, true},
+		{StateLobby, StateInRound, false},
+		{StateInRound, StateGenerating, false},
+		{StateInRound, StateLobby, false},
+		{StateFinished, StateInRound, false},
+		{StateClosed, StateLobby, false},
+	}
+	for _, tt := range tests {
+		room := Room{GameState: tt.from}
+		err := room.Transition(tt.to)
+		if tt.ok {
+			if err != nil || room.State() != tt.to {
+				t.Errorf("%s -> %s: err = %v, state = %s", tt.from, tt.to, err, room.State())
+			}
+			continue
+		}
+		var transitionErr *TransitionError
+		if !errors.As(err, &transitionErr) || room.State() != tt.from {
+			t.Errorf("%s -> %s: err = %v, state = %s; want a TransitionError", tt.from, tt.to, err, room.State())
+		}
+	}
+}
+
+func TestLegacyStatesAreLobby(t *testing.T) {
+	for _, state := range []GameState{"", "waiting"} {
+		room := Room{GameState: state}
+		if room.State() != StateLobby {
+			t.Errorf("State() of %q = %s, want lobby", state, room.State())
+		}
+		if err := room.Transition(StateGenerating); err != nil {
+			t.Errorf("%q -> generating: %v", state, err)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Question represents a single quiz question.
 type Question struct {
@@ -10,6 +14,8 @@ type Question struct {
 	AnswerOptions []string `json:"options"`
 	CorrectAnswer string   `json:"correct"`
 	PositionMs    int      `json:"positionMs"`
+	// SubmittedBy is the ID of the player who picked the track in the lobby.
+	SubmittedBy string `json:"submittedBy,omitempty"`
 }
 
 // Track represents a simplified track structure fetched from Spotify.
@@ -18,6 +24,29 @@ type Track struct {
 	Name     string   `json:"name"`
 	Artists  []string `json:"artists"`
 	Duration int      `json:"duration"`
+	Explicit bool     `json:"explicit,omitempty"`
+	// SubmittedBy is the ID of the player who picked the track in the lobby.
+	SubmittedBy string `json:"submittedBy,omitempty"`
+}
+
+// QuestionDraft holds questions generated in the background while the room
+// is still in the lobby. Key identifies the game mode, sources, settings and
+// players they were generated for; /start-game only uses a matching draft.
+type QuestionDraft struct {
+	Key       string     `json:"key"`
+	Questions []Question `json:"questions"`
+	// RemovedTracks counts the tracks each content filter left out.
+	RemovedTracks map[string]int `json:"removedTracks,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+}
+
+// CachedTracks are the tracks of a playlist or an artist kept between games.
+// Version is the playlist's snapshot ID or the ETag of the artist's albums;
+// the cache is reused while it still matches.
+type CachedTracks struct {
+	Tracks    []Track   `json:"tracks"`
+	Version   string    `json:"version,omitempty"`
+	FetchedAt time.Time `json:"fetchedAt"`
 }
 
 // Room holds the state of a quiz room.
@@ -26,14 +55,111 @@ type Room struct {
 	HostId      string         `json:"hostId"`
 	CreatedAt   time.Time      `json:"createdAt"`
 	Players     []string       `json:"players"`
-	GameState   string         `json:"gameState"`
+	GameState   GameState      `json:"gameState"`
 	CurrentQIdx int            `json:"currentQIdx"`
 	Scoreboard  map[string]int `json:"scoreboard"`
+	GameMode    string         `json:"gameMode,omitempty"`
+	QueryData   string         `json:"tracksData,omitempty"`
+	// Market is the host's Spotify country code, e.g. "PL". Tracks are only
+	// used if they can be played there.
+	Market string `json:"market,omitempty"`
+	// Sources are the track sources of a "mixed" game.
+	Sources []PoolSource `json:"sources,omitempty"`
+	// Profiles holds the display name and avatar of every player, keyed by player ID.
+	Profiles map[string]PlayerProfile `json:"profiles,omitempty"`
+	// SpotifyIDs maps player IDs to Spotify user IDs for players who joined with a token.
+	SpotifyIDs map[string]string `json:"spotifyIds,omitempty"`
+	// Settings are the game settings used by the next (or current) game.
+	Settings GameSettings `json:"settings"`
+	// Series holds the running score totals over all games played in this room.
+	Series      map[string]int `json:"series,omitempty"`
+	GamesPlayed int            `json:"gamesPlayed"`
+	// PlayedTracks holds the track IDs of the room's last games, oldest first.
+	PlayedTracks [][]string `json:"playedTracks,omitempty"`
+	// Ready lists the players who marked themselves ready in the lobby.
+	Ready []string `json:"ready,omitempty"`
+	// WaitingForHost is set when the host disconnected and no other player
+	// could take over. The quiz loop pauses until a host is back.
+	WaitingForHost bool `json:"waitingForHost,omitempty"`
+	// RoomSettings decide who may join the room.
+	RoomSettings RoomSettings `json:"roomSettings"`
+	// Banned lists the player IDs (lowercased) and Spotify user IDs the host
+	// banned; they cannot join the room again.
+	Banned           []string `json:"banned,omitempty"`
+	BannedSpotifyIDs []string `json:"bannedSpotifyIds,omitempty"`
+	// Submissions are the songs the players picked in the lobby. In "players"
+	// mode they replace the recently played tracks as the quiz pool. They are
+	// never sent to clients, so nobody learns the answers in advance.
+	Submissions []Track `json:"submissions,omitempty"`
+	// PasswordHash is the bcrypt hash of the join password. It is never sent to clients.
+	PasswordHash string `json:"passwordHash,omitempty"`
+	// Version is incremented on every update and used for optimistic concurrency.
+	Version int `json:"version"`
+}
+
+// PlayerProfile is how a player is shown to others. The ID never changes;
+// the display name can be edited.
+type PlayerProfile struct {
+	ID          string `json:"playerId"`
+	DisplayName string `json:"displayName"`
+	Avatar      string `json:"avatar"`
+}
+
+// Profile returns the player's profile. Players without a stored profile are
+// shown under their ID.
+func (r *Room) Profile(playerID string) PlayerProfile {
+	if profile, ok := r.Profiles[playerID]; ok {
+		return profile
+	}
+	return PlayerProfile{ID: playerID, DisplayName: playerID}
+}
+
+// RecentTrackIDs returns the IDs of the tracks played in the room's last n games.
+func (r *Room) RecentTrackIDs(n int) map[string]bool {
+	ids := make(map[string]bool)
+	for _, game := range r.PlayedTracks[max(len(r.PlayedTracks)-n, 0):] {
+		for _, id := range game {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// RecordPlayedTracks remembers the tracks of a finished game, keeping the
+// last MaxAvoidRecentGames games.
+func (r *Room) RecordPlayedTracks(ids []string) {
+	r.PlayedTracks = append(r.PlayedTracks, ids)
+	if len(r.PlayedTracks) > MaxAvoidRecentGames {
+		r.PlayedTracks = r.PlayedTracks[len(r.PlayedTracks)-MaxAvoidRecentGames:]
+	}
+}
+
+// PublicRoom is the lobby browser entry of a public room.
+type PublicRoom struct {
+	Code        string    `json:"roomCode"`
+	Title       string    `json:"title"`
+	GameMode    string    `json:"gameMode,omitempty"`
+	State       GameState `json:"state"`
+	HostName    string    `json:"hostName"`
+	Players     int       `json:"players"`
+	MaxPlayers  int       `json:"maxPlayers"`
+	HasPassword bool      `json:"hasPassword"`
+	LateJoin    bool      `json:"lateJoin"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ChatMessage is a chat line sent in a room.
+type ChatMessage struct {
+	PlayerID    string    `json:"playerId"`
+	DisplayName string    `json:"displayName"`
+	Text        string    `json:"text"`
+	SentAt      time.Time `json:"sentAt"`
 }
 
 // CreateRoomRequest is the request body for /create-room.
 type CreateRoomRequest struct {
-	HostID string `json:"hostId"`
+	HostID   string             `json:"hostId"`
+	Settings RoomSettingsUpdate `json:"settings"`
 }
 
 // RoomResponse is returned when creating a room.
@@ -42,17 +168,79 @@ type RoomResponse struct {
 }
 
 // JoinRoomRequest is the request body for /join-room.
+// PlayerID is optional: when it is empty the server assigns one. When
+// DisplayName is empty, PlayerID is used as the display name.
 type JoinRoomRequest struct {
+	RoomCode    string `json:"roomCode"`
+	PlayerID    string `json:"playerId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Password    string `json:"password,omitempty"`
+}
+
+// RenamePlayerRequest is the request body for /rename-player.
+type RenamePlayerRequest struct {
+	RoomCode    string `json:"roomCode"`
+	PlayerID    string `json:"playerId"`
+	DisplayName string `json:"displayName"`
+}
+
+// LeaveRoomRequest is the request body for /leave-room.
+type LeaveRoomRequest struct {
+	RoomCode string `json:"roomCode"`
+	PlayerID string `json:"playerId"`
+	// HostID lets the host remove a player; players leaving themselves send
+	// their access token instead.
+	HostID string `json:"hostId,omitempty"`
+}
+
+// KickPlayerRequest is the request body for /kick-player and /ban-player.
+type KickPlayerRequest struct {
 	RoomCode string `json:"roomCode"`
+	HostId   string `json:"hostId"`
 	PlayerID string `json:"playerId"`
 }
 
+// SongRequest is the request body for /submit-song and /remove-song.
+type SongRequest struct {
+	RoomCode string `json:"roomCode"`
+	PlayerID string `json:"playerId"`
+	TrackID  string `json:"trackId"`
+}
+
+// RoomSettingsRequest is the request body for /room-settings.
+type RoomSettingsRequest struct {
+	RoomCode string             `json:"roomCode"`
+	HostId   string             `json:"hostId"`
+	Settings RoomSettingsUpdate `json:"settings"`
+}
+
 // StartGameRequest is the request body for /start-game.
+// An empty GameMode reuses the mode stored on the room (e.g. chosen in /play-again).
+// Sources, if set, start a "mixed" game and replace GameMode and QueryData.
 type StartGameRequest struct {
-	RoomCode  string `json:"roomCode"`
-	HostId    string `json:"hostId"`
-	GameMode  string `json:"gameMode"`
-	QueryData string `json:"tracksData"`
+	RoomCode  string        `json:"roomCode"`
+	HostId    string        `json:"hostId"`
+	GameMode  string        `json:"gameMode"`
+	QueryData string        `json:"tracksData"`
+	Sources   []PoolSource  `json:"sources,omitempty"`
+	Settings  *GameSettings `json:"settings,omitempty"`
+}
+
+// PlayAgainRequest is the request body for /play-again. All fields except
+// RoomCode and HostId are optional and override the previous game's choices.
+type PlayAgainRequest struct {
+	RoomCode  string        `json:"roomCode"`
+	HostId    string        `json:"hostId"`
+	GameMode  string        `json:"gameMode,omitempty"`
+	QueryData string        `json:"tracksData,omitempty"`
+	Sources   []PoolSource  `json:"sources,omitempty"`
+	Settings  *GameSettings `json:"settings,omitempty"`
+}
+
+// CloseRoomRequest is the request body for /close-room.
+type CloseRoomRequest struct {
+	RoomCode string `json:"roomCode"`
+	HostId   string `json:"hostId"`
 }
 
 // AnswerRequest is the request body for /submit-answer.
@@ -63,8 +251,133 @@ type AnswerRequest struct {
 	PlayerID   string `json:"playerId"`
 }
 
-// ScoreEntry can be used for sorting or returning top players.
+// ScoreEntry is a player's line on a scoreboard.
 type ScoreEntry struct {
-	PlayerID string `json:"playerId"`
-	Score    int    `json:"score"`
+	PlayerID    string `json:"playerId"`
+	DisplayName string `json:"displayName"`
+	Avatar      string `json:"avatar,omitempty"`
+	Score       int    `json:"score"`
+}
+
+// AnswerRecord is a single player's answer to a question, stored for statistics.
+type AnswerRecord struct {
+	PlayerID   string `json:"playerId"`
+	Selected   string `json:"selected"`
+	Correct    bool   `json:"correct"`
+	ResponseMs int64  `json:"responseMs"`
+	Points     int    `json:"points"`
+}
+
+// QuestionStats describes how the players answered a single question.
+type QuestionStats struct {
+	QuestionID     string         `json:"questionId"`
+	TrackID        string         `json:"trackId"`
+	TrackName      string         `json:"trackName"`
+	CorrectAnswer  string         `json:"correct"`
+	CorrectPlayers []string       `json:"correctPlayers"`
+	FastestPlayer  string         `json:"fastestPlayer,omitempty"`
+	FastestMs      int64          `json:"fastestMs,omitempty"`
+	OptionCounts   map[string]int `json:"optionCounts"`
+	Answered       int            `json:"answered"`
+}
+
+// PlayerStats holds per-player accuracy and timing for a finished game.
+type PlayerStats struct {
+	PlayerID      string  `json:"playerId"`
+	DisplayName   string  `json:"displayName"`
+	Score         int     `json:"score"`
+	Answered      int     `json:"answered"`
+	Correct       int     `json:"correct"`
+	Accuracy      float64 `json:"accuracy"`
+	AvgResponseMs int64   `json:"avgResponseMs"`
+}
+
+// GameSummary is the post-game report sent as "game-summary" and served by /room/{code}/summary.
+type GameSummary struct {
+	RoomCode   string          `json:"roomCode"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Questions  []QuestionStats `json:"questions"`
+	Players    []PlayerStats   `json:"players"`
+	Scoreboard map[string]int  `json:"scoreboard"`
+	// Series is the running total over all games played in the room so far.
+	Series     map[string]int `json:"series"`
+	GameNumber int            `json:"gameNumber"`
+}
+
+// GameRecord is a finished game as kept in the persistent history store.
+type GameRecord struct {
+	ID         string                    `json:"id"`
+	RoomCode   string                    `json:"roomCode"`
+	HostID     string                    `json:"hostId"`
+	GameMode   string                    `json:"gameMode"`
+	QueryData  string                    `json:"tracksData"`
+	FinishedAt time.Time                 `json:"finishedAt"`
+	Questions  []Question                `json:"questions"`
+	Answers    map[string][]AnswerRecord `json:"answers"`
+	Ranking    []RankingEntry            `json:"ranking"`
+}
+
+// guestKeyPrefix starts the PlayerKey of players without a Spotify ID.
+const guestKeyPrefix = "guest:"
+
+// GuestKey is the PlayerKey of a player without a Spotify ID. Guest IDs are
+// free text, so the key is unique to the game and the player's position in
+// its ranking: guests never share a history or lifetime stats.
+func GuestKey(gameID string, position int) string {
+	return fmt.Sprintf("%s%s:%d", guestKeyPrefix, gameID, position)
+}
+
+// IsGuestKey reports whether the PlayerKey belongs to a guest.
+func IsGuestKey(playerKey string) bool {
+	return strings.HasPrefix(playerKey, guestKeyPrefix)
+}
+
+// RankingEntry is a player's final placement in a recorded game.
+// PlayerKey is the Spotify ID when known and a GuestKey otherwise.
+type RankingEntry struct {
+	PlayerKey     string `json:"playerKey"`
+	PlayerID      string `json:"playerId"`
+	SpotifyID     string `json:"spotifyId,omitempty"`
+	Rank          int    `json:"rank"`
+	Score         int    `json:"score"`
+	Correct       int    `json:"correct"`
+	Answered      int    `json:"answered"`
+	AvgResponseMs int64  `json:"avgResponseMs"`
+}
+
+// PlayerGame is a single entry of a player's game history.
+type PlayerGame struct {
+	GameID     string    `json:"gameId"`
+	RoomCode   string    `json:"roomCode"`
+	GameMode   string    `json:"gameMode"`
+	FinishedAt time.Time `json:"finishedAt"`
+	PlayerID   string    `json:"playerId"`
+	Rank       int       `json:"rank"`
+	Players    int       `json:"players"`
+	Score      int       `json:"score"`
+	Correct    int       `json:"correct"`
+	Answered   int       `json:"answered"`
+	Questions  int       `json:"questions"`
+}
+
+// LifetimeStats aggregates a player's results over all recorded games.
+type LifetimeStats struct {
+	PlayerKey     string  `json:"playerKey"`
+	GamesPlayed   int     `json:"gamesPlayed"`
+	Wins          int     `json:"wins"`
+	TotalScore    int     `json:"totalScore"`
+	BestScore     int     `json:"bestScore"`
+	AvgScore      float64 `json:"avgScore"`
+	Correct       int     `json:"correct"`
+	Answered      int     `json:"answered"`
+	Accuracy      float64 `json:"accuracy"`
+	AvgResponseMs int64   `json:"avgResponseMs"`
+}
+
+// UserToken holds the Spotify OAuth tokens of an authenticated user.
+// ExpiresAt is a Unix timestamp in seconds.
+type UserToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at"`
 }
@@ -0,0 +1,114 @@
+// Package moderation checks user-provided text such as display names.
+package moderation
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// defaultWords is the built-in block list, used unless PROFANITY_WORDS is set.
+// Words ending in "*" also block every word starting with them.
+var defaultWords = []string{
+	"fuck*", "shit*", "cunt*", "bitch*", "whore*", "slut*", "dick", "cock", "pussy", "pussies",
+	"nigger*", "nigga*", "faggot*", "retard", "retarded",
+	"kurw*", "chuj*", "huj*", "pierdol*", "jeba*", "pizd*", "cipa", "cipy", "dziwk*", "pedał", "pedały",
+}
+
+// leet maps common character substitutions back to letters.
+var leet = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b",
+	"@", "a", "$", "s", "!", "i", "|", "i",
+)
+
+// Filter rejects text containing blocked words. The text is split into words
+// at whitespace and every word is matched on its own, so "push it" is not
+// read as "pushit". Within a word, text is lowercased, common leetspeak
+// substitutions are undone and everything except letters is dropped, so
+// "F.u-c_k" and "sh1t" are caught as well. A blocked word matches whole
+// words and their plural in "s"; a blocked word ending in "*" matches every
+// word starting with it.
+type Filter struct {
+	words    map[string]bool
+	prefixes []string
+}
+
+// NewFilter returns a Filter blocking the given words.
+func NewFilter(words []string) *Filter {
+	filter := &Filter{words: make(map[string]bool)}
+	for _, word := range words {
+		word, prefix := strings.CutSuffix(strings.TrimSpace(word), "*")
+		normalized := lettersOnly(leet.Replace(strings.ToLower(word)))
+		switch {
+		case normalized == "":
+		case prefix:
+			filter.prefixes = append(filter.prefixes, normalized)
+		default:
+			filter.words[normalized] = true
+		}
+	}
+	return filter
+}
+
+// FilterFromEnv returns a Filter for the comma-separated PROFANITY_WORDS
+// environment variable, or for the built-in list if it is not set. Setting
+// PROFANITY_WORDS to "-" disables filtering.
+func FilterFromEnv() *Filter {
+	raw, ok := os.LookupEnv("PROFANITY_WORDS")
+	if !ok || raw == "" {
+		return NewFilter(defaultWords)
+	}
+	if raw == "-" {
+		return NewFilter(nil)
+	}
+	return NewFilter(strings.Split(raw, ","))
+}
+
+// Allowed reports whether the text contains none of the blocked words.
+func (f *Filter) Allowed(text string) bool {
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		for _, candidate := range candidates(word) {
+			if f.blocked(candidate) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// blocked reports whether the normalized word is on the block list.
+func (f *Filter) blocked(word string) bool {
+	if f.words[word] || f.words[strings.TrimSuffix(word, "s")] {
+		return true
+	}
+	for _, prefix := range f.prefixes {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the readings of one word of text: with and without the
+// leetspeak substitutions (a trailing "!" is punctuation more often than an
+// "i"), each as a whole with the non-letters dropped and split into its runs
+// of letters, so "hello,world" is checked as "helloworld", "hello" and "world".
+func candidates(word string) []string {
+	var words []string
+	for _, reading := range []string{leet.Replace(word), word} {
+		words = append(words, lettersOnly(reading))
+		words = append(words, strings.FieldsFunc(reading, func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})...)
+	}
+	return words
+}
+
+func lettersOnly(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, text)
+}
@@ -0,0 +1,69 @@
+package moderation
+
+import "testing"
+
+func TestFilterAllowed(t *testing.T) {
+	filter := NewFilter(defaultWords)
+	tests := []struct {
+		text string
+		want bool
+	}{
+		// Ordinary words and names containing a blocked word.
+		{"Hancock", true},
+		{"cocktail party", true},
+		{"Charles Dickens", true},
+		{"participate", true},
+		{"principal", true},
+		{"pedal", true},
+		{"push it", true},
+		{"sushi today", true},
+		{"Scunthorpe", true},
+		{"assassin", true},
+		{"", true},
+
+		// Real hits.
+		{"shit", false},
+		{"Holy shit!", false},
+		{"sh1t", false},
+		{"F.u-c_k", false},
+		{"fucking great", false},
+		{"BITCHES", false},
+		{"dicks", false},
+		{"what the fuck,man", false},
+		{"kurwa mać", false},
+		{"pedał", false},
+		{"jebać", false},
+	}
+	for _, tt := range tests {
+		if got := filter.Allowed(tt.text); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCustomWords(t *testing.T) {
+	filter := NewFilter([]string{"Banana", "grape*", " "})
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"banana", false},
+		{"b4nana", false},
+		{"bananas", false},
+		{"bananarama", true},
+		{"grapefruit", false},
+		{"apple", true},
+	}
+	for _, tt := range tests {
+		if got := filter.Allowed(tt.text); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFilterDisabled(t *testing.T) {
+	t.Setenv("PROFANITY_WORDS", "-")
+	if !FilterFromEnv().Allowed("shit") {
+		t.Error("PROFANITY_WORDS=- should allow everything")
+	}
+}
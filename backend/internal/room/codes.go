@@ -0,0 +1,83 @@
+package room
+
+import (
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultCodeAlphabet leaves out the look-alike characters 0/O and 1/I.
+	defaultCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	defaultCodeLength   = 6
+	defaultJoinBaseURL  = "http://localhost:5173"
+
+	// maxCodeAttempts is how many codes CreateRoomHandler tries before giving up.
+	maxCodeAttempts = 10
+)
+
+// Config holds the room code and join link settings.
+type Config struct {
+	// CodeAlphabet are the characters room codes are made of.
+	CodeAlphabet string
+	// CodeLength is the number of characters in a room code (4-12).
+	CodeLength int
+	// JoinBaseURL is the frontend URL that join links and QR codes point to.
+	JoinBaseURL string
+}
+
+// ConfigFromEnv reads the room configuration from ROOM_CODE_ALPHABET,
+// ROOM_CODE_LENGTH and JOIN_BASE_URL. Missing or invalid values fall back to
+// the defaults.
+func ConfigFromEnv() Config {
+	config := Config{
+		CodeAlphabet: defaultCodeAlphabet,
+		CodeLength:   defaultCodeLength,
+		JoinBaseURL:  defaultJoinBaseURL,
+	}
+
+	if raw := os.Getenv("ROOM_CODE_ALPHABET"); raw != "" {
+		if uniqueRunes(raw) >= 2 {
+			config.CodeAlphabet = raw
+		} else {
+			log.Printf("invalid ROOM_CODE_ALPHABET %q, using default", raw)
+		}
+	}
+	if raw := os.Getenv("ROOM_CODE_LENGTH"); raw != "" {
+		length, err := strconv.Atoi(raw)
+		if err == nil && length >= 4 && length <= 12 {
+			config.CodeLength = length
+		} else {
+			log.Printf("invalid ROOM_CODE_LENGTH %q, using default", raw)
+		}
+	}
+	if raw := os.Getenv("JOIN_BASE_URL"); raw != "" {
+		config.JoinBaseURL = strings.TrimRight(raw, "/")
+	}
+	return config
+}
+
+func uniqueRunes(s string) int {
+	seen := make(map[rune]bool)
+	for _, r := range s {
+		seen[r] = true
+	}
+	return len(seen)
+}
+
+// generateRoomCode returns a random code of CodeLength characters from CodeAlphabet.
+func (c Config) generateRoomCode() string {
+	alphabet := []rune(c.CodeAlphabet)
+	code := make([]rune, c.CodeLength)
+	for i := range code {
+		code[i] = alphabet[rand.IntN(len(alphabet))]
+	}
+	return string(code)
+}
+
+// joinURL returns the link players can open to join the room.
+func (c Config) joinURL(roomCode string) string {
+	return c.JoinBaseURL + "/?join=" + roomCode
+}
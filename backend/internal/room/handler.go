@@ -1,36 +1,65 @@
 package room
 
 import (
+	"backend/internal/apierror"
+	"backend/internal/avatar"
 	"backend/internal/model"
+	"backend/internal/moderation"
 	"backend/internal/spotify"
 	"backend/internal/store"
 	"backend/internal/ws"
 	"encoding/json"
+	"errors"
 	"log"
-	"math/rand"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
 
-func generateRoomCode() string {
-	code := ""
-	characters := "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	for range 6 {
-		code += string(characters[rand.Intn(len(characters))])
-	}
+// Handler serves the room endpoints. It reads and writes room data through
+// the repository and notifies connected clients through the hub.
+type Handler struct {
+	repo    store.Repository
+	hub     *ws.Hub
+	config  Config
+	filter  *moderation.Filter
+	spotify *spotify.Client
+}
 
-	return code
+// NewHandler returns a room Handler using the given repository, hub and
+// configuration. Display names are checked against the filter.
+func NewHandler(repo store.Repository, hub *ws.Hub, config Config, filter *moderation.Filter, client *spotify.Client) *Handler {
+	return &Handler{repo: repo, hub: hub, config: config, filter: filter, spotify: client}
 }
 
+var (
+	errPlayerExists   = errors.New("player already exists")
+	errGameInProgress = errors.New("game already in progress")
+	errNotAccepting   = errors.New("room is not accepting players")
+)
+
 // CreateRoomHandler handles HTTP POST requests to /create-room.
 //
 // It expects a JSON payload in the following format:
 //
 //	{
-//	  "hostId": "spotify-user-id"
+//	  "hostId": "spotify-user-id",
+//	  "settings": {
+//	    "maxPlayers": 8,
+//	    "password": "secret",
+//	    "lateJoin": false,
+//	    "locked": false,
+//	    "public": false,
+//	    "title": "Friday 90s night",
+//	    "gameMode": "playlist"
+//	  }
 //	}
 //
+// "settings" and each of its fields are optional. maxPlayers defaults to 8 and
+// must be between 1 and 20; an empty password means anyone with the code may join.
+// Public rooms are listed in the lobby browser, see /room-settings.
+//
 // The request **must** include an Authorization header with a Spotify access token:
 //
 //	Authorization: Bearer <access_token>
@@ -44,11 +73,17 @@ func generateRoomCode() string {
 //
 //  3. Stores the access token in Redis under the key "player:{hostId}".
 //
-//  4. Generates a 6-character room code.
+//  4. Generates a room code (6 characters without look-alikes such as 0/O and
+//     1/I by default, see ROOM_CODE_ALPHABET and ROOM_CODE_LENGTH).
 //
-//  5. Constructs a new Room object with the given hostId and state set to "waiting".
+//  5. Constructs a new Room object with the given hostId, room settings and
+//     state set to "lobby", remembering the host's Spotify user ID (from /me)
+//     for the game history. The password is stored only as a bcrypt hash.
 //
 //  6. Stores the Room in Redis under the key "room:{roomCode}" with a 60-minute TTL.
+//     The key is only set if it does not exist yet (SETNX); if the code is taken
+//     by a live room, a new code is generated, up to 10 times. A game summary
+//     left under the code by an earlier room is deleted.
 //
 //  7. Responds with a JSON object containing the generated room code:
 //
@@ -58,7 +93,10 @@ func generateRoomCode() string {
 //     }
 //
 // On JSON parsing failure or Redis write failure, responds with an appropriate HTTP 400/500 status.
-func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
+// If no free code is found, responds with 503 and the "no_free_code" code.
+// Invalid settings are rejected with 400 and the "invalid_settings" code, a
+// blocked title with 400 and "title_not_allowed".
+func (h *Handler) CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 	var request model.CreateRoomRequest
 	room := new(model.Room)
 	err := json.NewDecoder(r.Body).Decode(&request)
@@ -66,6 +104,14 @@ func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
+	if err := request.Settings.Validate(); err != nil {
+		apierror.Write(w, http.StatusBadRequest, "invalid_settings", err.Error())
+		return
+	}
+	if err := h.cleanTitle(&request.Settings); err != nil {
+		writeRoomError(w, err)
+		return
+	}
 
 	room.HostId = request.HostID
 
@@ -78,26 +124,51 @@ func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	token = strings.TrimSpace(token)
 
-	userKey := "player:" + room.HostId
-	tokenData, _ := json.Marshal(map[string]string{
-		"access_token": token,
-	})
-	err = store.Client.Set(store.Ctx, userKey, tokenData, 60*time.Minute).Err()
+	err = h.repo.SavePlayerToken(r.Context(), room.HostId, token)
 	if err != nil {
 		log.Println("Failed to save token during room creation:", err)
 	}
 
-	room.Code = generateRoomCode()
 	room.CreatedAt = time.Now()
-	room.GameState = "waiting"
+	room.GameState = model.StateLobby
 
-	data, _ := json.Marshal(room)
-	roomKey := "room:" + room.Code
-	err = store.Client.Set(store.Ctx, roomKey, data, 60*time.Minute).Err()
+	passwordHash := ""
+	if request.Settings.Password != nil {
+		passwordHash, err = hashPassword(*request.Settings.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	applySettings(room, request.Settings, passwordHash)
+
+	profile, err := h.spotify.Me(r.Context(), token)
+	if err != nil {
+		log.Println("Failed to fetch host profile:", err)
+	} else {
+		room.SpotifyIDs = map[string]string{room.HostId: profile.ID}
+		room.Market = profile.Country
+	}
+
+	for range maxCodeAttempts {
+		room.Code = h.config.generateRoomCode()
+		err = h.repo.CreateRoom(r.Context(), *room)
+		if !errors.Is(err, store.ErrCodeTaken) {
+			break
+		}
+		log.Printf("Room code %s is taken, generating another one", room.Code)
+	}
+	if errors.Is(err, store.ErrCodeTaken) {
+		apierror.Write(w, http.StatusServiceUnavailable, "no_free_code", "No free room code found, please try again")
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if err := h.repo.DeleteSummary(r.Context(), room.Code); err != nil {
+		log.Println("Failed to delete the summary of an earlier room:", err)
+	}
 	err = json.NewEncoder(w).Encode(map[string]string{
 		"RoomCode": room.Code,
 	})
@@ -109,36 +180,70 @@ func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 //
 //	{
 //	  "roomCode": "ABC123",
-//	  "playerId": "spotify-user-456"
+//	  "displayName": "DJ Kasia",
+//	  "playerId": "p-3f9a0c12de", // optional
+//	  "password": "secret"        // only for rooms with a password
 //	}
 //
-// The handler performs the following steps:
-//
-//  1. Decodes the request body into a JoinRoomRequest struct.
+// Players have a stable ID and an editable display name. Without a playerId
+// the server assigns one; without a displayName the playerId is shown.
 //
-//  2. Retrieves the Room object from Redis under "room:{roomCode}".
-//     - If not found, responds with HTTP 404.
-//
-//  3. Check if player with the same name already exists
-//     - If yes, respond with HTTP 409.
-//
-//  4. Appends the joining playerId to the room's Players slice.
-//
-//  5. Updates the room in Redis with a 60-minute TTL.
+// The handler performs the following steps:
 //
-//  6. If the request contains a valid Authorization header:
+//  1. Decodes the request body into a JoinRoomRequest struct and validates the
+//     display name: 2-20 characters, passing the profanity filter.
+//
+//  2. Checks the room settings and the password. Each rejection has its own
+//     error code, returned as { "error": "<code>", "message": "..." }:
+//     - "invalid_display_name" (400): the display name is too short or too long.
+//     - "display_name_not_allowed" (400): the display name failed the profanity filter.
+//     - "room_not_found" (404): the room does not exist.
+//     - "player_banned" (403): the host banned this player, name or Spotify account.
+//     - "room_locked" (403): the host locked the lobby.
+//     - "game_in_progress" (409): a game is running and late join is off.
+//     - "invalid_state" (409): the game has finished or the room is closing.
+//     - "room_full" (409): the room already has maxPlayers players.
+//     - "password_required" (401): the room has a password and none was sent.
+//     - "wrong_password" (403): the password does not match.
+//     - "player_exists" (409): a player with the same ID already exists.
+//     - "display_name_taken" (409): another player uses the display name (ignoring case).
+//
+//  3. Updates the Room stored under "room:{roomCode}" with an optimistic,
+//     retried transaction, so two players joining at once never overwrite each
+//     other or overfill the room. The checks above are repeated on the fresh
+//     room, the player ID is appended to the room's Players slice and the
+//     player's profile is stored in the room's Profiles.
+//     If late join is on, players may also join a running game; they start
+//     with zero points and play from the next question.
+//
+//  4. If the request carries a Spotify token, the player's Spotify user ID is
+//     stored in the room's SpotifyIDs as part of the same update, and their
+//     Spotify profile image becomes their avatar. Other players get a
+//     generated identicon ("/avatars/{playerId}.svg").
+//
+//  5. If the room keeps changing and the update cannot be applied after
+//     several retries, responds with HTTP 409 ("room_busy").
+//
+//  6. The room is saved with a 60-minute TTL.
+//
+//  7. If the request contains a valid Authorization header:
 //     - Extracts the Spotify access token.
-//     - Fetches the player's 25 most recently played tracks via Spotify API.
+//     - Fetches the player's tracks from the source in the room's game
+//     settings ("trackSource"): the 25 most recently played tracks by
+//     default, or up to 100 top tracks ("timeRange") or liked songs.
 //     - Stores the tracks in Redis under "tracks:{roomCode}:{playerId}".
 //     - Also stores the access token in Redis under "player:{playerId}".
 //
-//  7. Responds with a JSON object confirming the join:
+//  8. Broadcasts the new player's profile as "new-player" and responds with a
+//     JSON object confirming the join:
 //
 //     Response:
 //     {
 //     "status": "joined",
 //     "roomCode": "ABC123",
-//     "playerId": "spotify-user-456"
+//     "playerId": "p-3f9a0c12de",
+//     "displayName": "DJ Kasia",
+//     "avatar": "/avatars/p-3f9a0c12de.svg"
 //     }
 //
 // Notes:
@@ -146,90 +251,205 @@ func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 // - If the Spotify token is expired or invalid, track saving will silently fail.
 //
 // On JSON parsing failure or Redis error, responds with appropriate HTTP 400/500.
-func JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
 	var request model.JoinRoomRequest
 	err := json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
-	roomKey := "room:" + request.RoomCode
-	data, err := store.Client.Get(store.Ctx, roomKey).Result()
+
+	playerID := strings.TrimSpace(request.PlayerID)
+	displayName := request.DisplayName
+	if displayName == "" {
+		displayName = playerID
+	}
+	displayName, err = h.cleanDisplayName(displayName)
 	if err != nil {
-		http.Error(w, "Room not found", http.StatusNotFound)
+		writeRoomError(w, err)
 		return
 	}
+	if playerID == "" {
+		playerID = newPlayerID()
+	}
 
-	var room model.Room
-
-	err = json.Unmarshal([]byte(data), &room)
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err == nil {
+		err = checkJoin(room, playerID, displayName)
+	}
 	if err != nil {
-		http.Error(w, "internal error (unmarshal)", http.StatusInternalServerError)
+		writeRoomError(w, err)
 		return
 	}
-
-	normalized := strings.ToLower(strings.TrimSpace(request.PlayerID))
-	for _, player := range room.Players {
-		if strings.TrimSpace(strings.ToLower(player)) == normalized {
-			http.Error(w, "Player already exists", http.StatusConflict)
-			return
-		}
+	if room.PasswordHash != "" && request.Password == "" {
+		apierror.Write(w, http.StatusUnauthorized, "password_required", "This room requires a password")
+		return
 	}
-
-	room.Players = append(room.Players, request.PlayerID)
-
-	jsonData, _ := json.Marshal(room)
-	err = store.Client.Set(store.Ctx, roomKey, string(jsonData), 60*time.Minute).Err()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !checkPassword(room, request.Password) {
+		writeRoomError(w, errWrongPassword)
 		return
 	}
+	checkedHash := room.PasswordHash
 
 	authHeader := r.Header.Get("Authorization")
-	if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		token = strings.TrimSpace(token)
-		tracks, err := spotify.FetchRecentTracks(token)
+	hasToken := authHeader != "" && strings.HasPrefix(authHeader, "Bearer ")
+	token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+
+	profile := model.PlayerProfile{
+		ID:          playerID,
+		DisplayName: displayName,
+		Avatar:      avatar.URL(playerID),
+	}
+	spotifyID := ""
+	if hasToken {
+		spotifyProfile, err := h.spotify.Me(r.Context(), token)
 		if err != nil {
-			panic(err)
+			log.Println("Failed to fetch player profile:", err)
+		} else {
+			spotifyID = spotifyProfile.ID
+			if len(spotifyProfile.Images) > 0 {
+				profile.Avatar = spotifyProfile.Images[0].URL
+			}
 		}
+	}
 
-		jsonData, _ := json.Marshal(tracks)
+	_, err = h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if err := checkJoin(*room, playerID, displayName); err != nil {
+			return err
+		}
+		if isBanned(*room, spotifyID) {
+			return errPlayerBanned
+		}
+		// The host changed the password after it was checked.
+		if room.PasswordHash != checkedHash {
+			return errWrongPassword
+		}
 
-		err = store.Client.Set(store.Ctx, "tracks:"+request.RoomCode+":"+request.PlayerID, jsonData, 60*time.Minute).Err()
+		room.Players = append(room.Players, playerID)
+		if room.Profiles == nil {
+			room.Profiles = make(map[string]model.PlayerProfile)
+		}
+		room.Profiles[playerID] = profile
+		if spotifyID != "" {
+			if room.SpotifyIDs == nil {
+				room.SpotifyIDs = make(map[string]string)
+			}
+			room.SpotifyIDs[playerID] = spotifyID
+		}
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
 
+	if hasToken {
+		settings := room.Settings.WithDefaults()
+		tracks, err := h.spotify.UserTracks(r.Context(), token, settings.TrackSource, settings.TimeRange, room.Market)
 		if err != nil {
+			log.Printf("error fetching %s tracks: %v", settings.TrackSource, err)
+		} else if err := h.repo.SaveTracks(r.Context(), request.RoomCode, playerID, tracks); err != nil {
 			log.Println("error saving tracks:", err)
 		} else {
 			log.Println("saved tracks:", len(tracks))
 		}
-		userKey := "player:" + request.PlayerID
-		tokenData, _ := json.Marshal(map[string]string{
-			"access_token": token,
-		})
-
-		err = store.Client.Set(store.Ctx, userKey, tokenData, 60*time.Minute).Err()
+		err = h.repo.SavePlayerToken(r.Context(), playerID, token)
 		if err != nil {
 			log.Println("error saving user token", err)
 		}
 	}
-	message := map[string]any{
-		"type": "new-player",
-		"data": request.PlayerID,
-	}
-	payload, _ := json.Marshal(message)
-	ws.GlobalHub.Broadcast <- ws.BroadcastMessage{
-		RoomCode: request.RoomCode,
-		Data:     payload,
-	}
+	h.hub.Emit(request.RoomCode, "new-player", profile)
 
 	json.NewEncoder(w).Encode(map[string]string{
-		"status":   "joined",
-		"roomCode": request.RoomCode,
-		"playerId": request.PlayerID,
+		"status":      "joined",
+		"roomCode":    request.RoomCode,
+		"playerId":    playerID,
+		"displayName": profile.DisplayName,
+		"avatar":      profile.Avatar,
 	})
 }
 
+// checkJoin reports why the player may not join the room, or nil if they may.
+// The password and the Spotify account ban are checked separately.
+func checkJoin(room model.Room, playerID, displayName string) error {
+	switch room.State() {
+	case model.StateLobby:
+	case model.StateGenerating, model.StateInRound, model.StateReveal:
+		if !room.RoomSettings.LateJoin {
+			return errGameInProgress
+		}
+	default:
+		return errNotAccepting
+	}
+	if isBanned(room, "", playerID, displayName) {
+		return errPlayerBanned
+	}
+	if room.RoomSettings.Locked {
+		return errRoomLocked
+	}
+	if len(room.Players) >= room.RoomSettings.Capacity() {
+		return errRoomFull
+	}
+
+	if slices.Contains(room.Players, playerID) {
+		return errPlayerExists
+	}
+	if nameTaken(room, displayName, "") {
+		return errNameTaken
+	}
+	return nil
+}
+
+// writeRoomError maps a join or room update error to its API error response.
+func writeRoomError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		apierror.Write(w, http.StatusNotFound, "room_not_found", "Room not found")
+	case errors.Is(err, errRoomLocked):
+		apierror.Write(w, http.StatusForbidden, "room_locked", "The room is locked")
+	case errors.Is(err, errGameInProgress):
+		apierror.Write(w, http.StatusConflict, "game_in_progress", "The game has already started")
+	case errors.Is(err, errNotAccepting):
+		apierror.Write(w, http.StatusConflict, "invalid_state", "The room is not accepting players")
+	case errors.Is(err, errRoomFull):
+		apierror.Write(w, http.StatusConflict, "room_full", "The room is full")
+	case errors.Is(err, errWrongPassword):
+		apierror.Write(w, http.StatusForbidden, "wrong_password", "Wrong room password")
+	case errors.Is(err, errPlayerExists):
+		apierror.Write(w, http.StatusConflict, "player_exists", "A player with this ID already exists")
+	case errors.Is(err, errInvalidName):
+		apierror.Write(w, http.StatusBadRequest, "invalid_display_name", err.Error())
+	case errors.Is(err, errNameNotAllowed):
+		apierror.Write(w, http.StatusBadRequest, "display_name_not_allowed", err.Error())
+	case errors.Is(err, errNameTaken):
+		apierror.Write(w, http.StatusConflict, "display_name_taken", err.Error())
+	case errors.Is(err, errTitleNotAllowed):
+		apierror.Write(w, http.StatusBadRequest, "title_not_allowed", err.Error())
+	case errors.Is(err, errPlayerBanned):
+		apierror.Write(w, http.StatusForbidden, "player_banned", "You are banned from this room")
+	case errors.Is(err, errNotAllowed):
+		apierror.Write(w, http.StatusForbidden, "not_allowed", err.Error())
+	case errors.Is(err, errRemoveHost):
+		apierror.Write(w, http.StatusBadRequest, "cannot_remove_host", err.Error())
+	case errors.Is(err, errNotInRoom):
+		apierror.Write(w, http.StatusForbidden, "not_in_room", err.Error())
+	case errors.Is(err, errPlayerNotFound):
+		apierror.Write(w, http.StatusNotFound, "player_not_found", "Player is not in the room")
+	case errors.Is(err, errNotInLobby):
+		apierror.Write(w, http.StatusConflict, "invalid_state", "The room is not in the lobby")
+	case errors.Is(err, errSongLimit):
+		apierror.Write(w, http.StatusConflict, "song_limit_reached", err.Error())
+	case errors.Is(err, errSongSubmitted):
+		apierror.Write(w, http.StatusConflict, "song_already_submitted", err.Error())
+	case errors.Is(err, errSongNotFound):
+		apierror.Write(w, http.StatusNotFound, "song_not_found", err.Error())
+	case errors.Is(err, store.ErrConflict):
+		apierror.Write(w, http.StatusConflict, "room_busy", "Room is busy, please try again")
+	default:
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+	}
+}
+
 // GetRoomHandler handles HTTP GET requests to /room/{code}.
 //
 // It extracts the room code from the URL path, for example:
@@ -252,30 +472,33 @@ func JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
 //     "hostId": "host123",
 //     "createdAt": "...",
 //     "players": ["player1", "player2"],
-//     "gameState": "waiting",
+//     "gameState": "lobby",
 //     "currentQIdx": 0,
-//     "scoreboard": { ... }
+//     "scoreboard": { ... },
+//     "profiles": { "player1": { "playerId": "player1", "displayName": "DJ Kasia", "avatar": "..." } },
+//     "roomSettings": { "maxPlayers": 8, "hasPassword": false, "lateJoin": false, "locked": false }
 //     }
 //
+// The password hash and the submitted songs are left out.
+//
 // If the room does not exist or the URL is malformed, responds with a 404 or 500 status code.
-func GetRoomHandler(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetRoomHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 3 || parts[2] == "" {
 		http.Error(w, "Room not found", http.StatusNotFound)
 		return
 	}
 
-	roomKey := "room:" + parts[2]
-	data, err := store.Client.Get(store.Ctx, roomKey).Result()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	room, err := h.repo.GetRoom(r.Context(), parts[2])
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Room not found", http.StatusNotFound)
 		return
 	}
-	var room model.Room
-	err = json.Unmarshal([]byte(data), &room)
 	if err != nil {
-		http.Error(w, "failed to parse room data", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	room.PasswordHash = ""
+	room.Submissions = nil
 	json.NewEncoder(w).Encode(room)
 }
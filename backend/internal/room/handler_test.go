@@ -0,0 +1,188 @@
+package room
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"backend/internal/moderation"
+	"backend/internal/store"
+	"backend/internal/ws"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestHandler(t *testing.T, room model.Room) (*Handler, *store.MemoryRepository) {
+	t.Helper()
+	repo := store.NewMemoryRepository()
+	if err := repo.CreateRoom(context.Background(), room); err != nil {
+		t.Fatal(err)
+	}
+	hub := ws.NewHub()
+	go hub.Run()
+	return NewHandler(repo, hub, ConfigFromEnv(), moderation.NewFilter([]string{"shit*"}), nil), repo
+}
+
+// post calls the handler with the JSON body and returns the response.
+func post(handler http.HandlerFunc, body any, header http.Header) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(data)))
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// errorCode returns the "error" code of an API error response.
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apierror.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response %d %q is not an API error", rec.Code, rec.Body.String())
+	}
+	return body.Code
+}
+
+func TestJoinRoom(t *testing.T) {
+	passwordHash, err := hashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, repo := newTestHandler(t, model.Room{
+		Code:         "ABC123",
+		HostId:       "host",
+		Players:      []string{"host"},
+		PasswordHash: passwordHash,
+		RoomSettings: model.RoomSettings{MaxPlayers: 3, HasPassword: true},
+	})
+
+	rec := post(h.JoinRoomHandler, model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p1", DisplayName: "Kasia", Password: "secret"}, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("join: %d %s", rec.Code, rec.Body)
+	}
+	room, _ := repo.GetRoom(context.Background(), "ABC123")
+	if !slices.Contains(room.Players, "p1") || room.Profile("p1").DisplayName != "Kasia" {
+		t.Fatalf("room after join = %+v", room)
+	}
+
+	tests := []struct {
+		name    string
+		request model.JoinRoomRequest
+		status  int
+		code    string
+	}{
+		{"missing room", model.JoinRoomRequest{RoomCode: "NOPE99", PlayerID: "p2"}, http.StatusNotFound, "room_not_found"},
+		{"same ID", model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p1", DisplayName: "Other", Password: "secret"}, http.StatusConflict, "player_exists"},
+		{"no password", model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p2"}, http.StatusUnauthorized, "password_required"},
+		{"wrong password", model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p2", Password: "guess"}, http.StatusForbidden, "wrong_password"},
+		{"same name", model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p2", DisplayName: "KASIA", Password: "secret"}, http.StatusConflict, "display_name_taken"},
+		{"short name", model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p2", DisplayName: "K", Password: "secret"}, http.StatusBadRequest, "invalid_display_name"},
+		{"blocked name", model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p2", DisplayName: "shithead", Password: "secret"}, http.StatusBadRequest, "display_name_not_allowed"},
+	}
+	for _, tt := range tests {
+		rec := post(h.JoinRoomHandler, tt.request, nil)
+		if rec.Code != tt.status || errorCode(t, rec) != tt.code {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+
+	// The third player fills the room.
+	if rec := post(h.JoinRoomHandler, model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p2", DisplayName: "Ola", Password: "secret"}, nil); rec.Code != http.StatusOK {
+		t.Fatalf("join: %d %s", rec.Code, rec.Body)
+	}
+	rec = post(h.JoinRoomHandler, model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p3", DisplayName: "Ela", Password: "secret"}, nil)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "room_full" {
+		t.Fatalf("join a full room: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestJoinRoomSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    model.GameState
+		settings model.RoomSettings
+		status   int
+		code     string
+	}{
+		{"running game", model.StateInRound, model.RoomSettings{}, http.StatusConflict, "game_in_progress"},
+		{"late join", model.StateInRound, model.RoomSettings{LateJoin: true}, http.StatusOK, ""},
+		{"locked lobby", model.StateLobby, model.RoomSettings{Locked: true}, http.StatusForbidden, "room_locked"},
+		{"finished game", model.StateFinished, model.RoomSettings{LateJoin: true}, http.StatusConflict, "invalid_state"},
+	}
+	for _, tt := range tests {
+		h, _ := newTestHandler(t, model.Room{Code: "ABC123", HostId: "host", Players: []string{"host"}, GameState: tt.state, RoomSettings: tt.settings})
+		rec := post(h.JoinRoomHandler, model.JoinRoomRequest{RoomCode: "ABC123", PlayerID: "p1", DisplayName: "Kasia"}, nil)
+		if rec.Code != tt.status || (tt.code != "" && errorCode(t, rec) != tt.code) {
+			t.Errorf("%s: %d %s, want %d %s", tt.name, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+}
+
+func TestLeaveRoomAuthorization(t *testing.T) {
+	h, repo := newTestHandler(t, model.Room{Code: "ABC123", HostId: "host", Players: []string{"host", "p1", "p2"}})
+	repo.SavePlayerToken(context.Background(), "p1", "token-p1")
+
+	bearer := func(token string) http.Header {
+		return http.Header{"Authorization": {"Bearer " + token}}
+	}
+	tests := []struct {
+		name    string
+		request model.LeaveRoomRequest
+		header  http.Header
+		status  int
+	}{
+		{"no credentials", model.LeaveRoomRequest{RoomCode: "ABC123", PlayerID: "p1"}, nil, http.StatusForbidden},
+		{"someone else's token", model.LeaveRoomRequest{RoomCode: "ABC123", PlayerID: "p1"}, bearer("token-p2"), http.StatusForbidden},
+		{"wrong host", model.LeaveRoomRequest{RoomCode: "ABC123", PlayerID: "p2", HostID: "p1"}, nil, http.StatusForbidden},
+		{"own token", model.LeaveRoomRequest{RoomCode: "ABC123", PlayerID: "p1"}, bearer("token-p1"), http.StatusOK},
+		{"host", model.LeaveRoomRequest{RoomCode: "ABC123", PlayerID: "p2", HostID: "host"}, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := post(h.LeaveRoomHandler, tt.request, tt.header); rec.Code != tt.status {
+			t.Errorf("%s: %d %s, want %d", tt.name, rec.Code, rec.Body, tt.status)
+		}
+	}
+	if room, _ := repo.GetRoom(context.Background(), "ABC123"); !slices.Equal(room.Players, []string{"host"}) {
+		t.Fatalf("players = %v, want only the host", room.Players)
+	}
+}
+
+func TestAdmitPlayer(t *testing.T) {
+	h, _ := newTestHandler(t, model.Room{Code: "ABC123", HostId: "host", Players: []string{"host", "p1", "troll"}, Banned: []string{"troll"}})
+	tests := []struct {
+		roomCode, playerID string
+		status             int
+		code               string
+	}{
+		{"ABC123", "p1", http.StatusOK, ""},
+		{"ABC123", "host", http.StatusOK, ""},
+		{"ABC123", "stranger", http.StatusForbidden, "not_in_room"},
+		{"ABC123", "troll", http.StatusForbidden, "player_banned"},
+		{"NOPE99", "p1", http.StatusNotFound, "room_not_found"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		ok := h.AdmitPlayer(rec, httptest.NewRequest(http.MethodGet, "/ws/"+tt.roomCode+"/"+tt.playerID, nil), tt.roomCode, tt.playerID)
+		if ok != (tt.code == "") || rec.Code != tt.status || (tt.code != "" && errorCode(t, rec) != tt.code) {
+			t.Errorf("%s in %s: admitted = %v, %d %s; want %d %s", tt.playerID, tt.roomCode, ok, rec.Code, rec.Body, tt.status, tt.code)
+		}
+	}
+}
+
+func TestKickHost(t *testing.T) {
+	h, repo := newTestHandler(t, model.Room{Code: "ABC123", HostId: "host", Players: []string{"host", "p1"}})
+	for _, handler := range []http.HandlerFunc{h.KickPlayerHandler, h.BanPlayerHandler} {
+		rec := post(handler, model.KickPlayerRequest{RoomCode: "ABC123", HostId: "host", PlayerID: "Host"}, nil)
+		if rec.Code != http.StatusBadRequest || errorCode(t, rec) != "cannot_remove_host" {
+			t.Errorf("host removing themselves: %d %s", rec.Code, rec.Body)
+		}
+	}
+	if room, _ := repo.GetRoom(context.Background(), "ABC123"); !slices.Contains(room.Players, "host") || len(room.Banned) != 0 {
+		t.Fatalf("room = %+v, want the host still in it", room)
+	}
+}
@@ -0,0 +1,164 @@
+package room
+
+import (
+	"backend/internal/model"
+	"backend/internal/store"
+	"backend/internal/ws"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const defaultHostGracePeriod = 30 * time.Second
+
+var errHostUnchanged = errors.New("host unchanged")
+
+// HostGracePeriod returns how long a host may be disconnected before host
+// rights move on, read from HOST_GRACE_PERIOD (default 30s).
+func HostGracePeriod() time.Duration {
+	raw := os.Getenv("HOST_GRACE_PERIOD")
+	if raw == "" {
+		return defaultHostGracePeriod
+	}
+	grace, err := time.ParseDuration(raw)
+	if err != nil || grace <= 0 {
+		log.Printf("invalid HOST_GRACE_PERIOD %q, using default", raw)
+		return defaultHostGracePeriod
+	}
+	return grace
+}
+
+// HostMonitor watches the hosts' WebSocket connections. When a host has been
+// disconnected for longer than the grace period, host rights move to another
+// connected player with a Spotify token. If there is none, the room is marked
+// as waiting for a host, which pauses the quiz loop before the next question,
+// until the host reconnects or a player with a token connects.
+//
+// Every change is broadcast to the room:
+//
+//	{
+//	  "type": "host-changed",
+//	  "data": {
+//	    "hostId": "p-3f9a0c12de",
+//	    "hostName": "DJ Kasia",
+//	    "previousHostId": "spotify-user-456",
+//	    "waitingForHost": false
+//	  }
+//	}
+type HostMonitor struct {
+	repo  store.Repository
+	hub   *ws.Hub
+	grace time.Duration
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// NewHostMonitor returns a HostMonitor. Register its Presence method with
+// the hub's OnPresence before starting the hub.
+func NewHostMonitor(repo store.Repository, hub *ws.Hub, grace time.Duration) *HostMonitor {
+	return &HostMonitor{
+		repo:   repo,
+		hub:    hub,
+		grace:  grace,
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Presence is a ws.PresenceFunc. It starts the grace period when the host
+// goes offline and cancels it when the host comes back.
+func (m *HostMonitor) Presence(roomCode, playerID string, online bool) {
+	ctx := context.Background()
+	room, err := m.repo.GetRoom(ctx, roomCode)
+	if err != nil {
+		return
+	}
+
+	switch {
+	case playerID == room.HostId && !online:
+		m.mu.Lock()
+		if _, ok := m.timers[roomCode]; !ok {
+			m.timers[roomCode] = time.AfterFunc(m.grace, func() {
+				m.mu.Lock()
+				delete(m.timers, roomCode)
+				m.mu.Unlock()
+				m.migrate(ctx, roomCode)
+			})
+		}
+		m.mu.Unlock()
+	case playerID == room.HostId && online:
+		m.mu.Lock()
+		if timer, ok := m.timers[roomCode]; ok {
+			timer.Stop()
+			delete(m.timers, roomCode)
+		}
+		m.mu.Unlock()
+		if room.WaitingForHost {
+			m.migrate(ctx, roomCode)
+		}
+	case online && room.WaitingForHost:
+		// A player who can take over may have just connected.
+		m.migrate(ctx, roomCode)
+	}
+}
+
+// migrate hands host rights to the first connected player (in join order)
+// with a Spotify token, or marks the room as waiting for a host. It does
+// nothing if the host is connected and the room is not waiting.
+func (m *HostMonitor) migrate(ctx context.Context, roomCode string) {
+	online := m.hub.Online(roomCode)
+	var candidates []string
+	for _, playerID := range online {
+		if _, err := m.repo.GetPlayerToken(ctx, playerID); err == nil {
+			candidates = append(candidates, playerID)
+		}
+	}
+
+	previous := ""
+	room, err := m.repo.UpdateRoom(ctx, roomCode, func(room *model.Room) error {
+		previous = room.HostId
+		if room.State() == model.StateClosed {
+			return errHostUnchanged
+		}
+		if slices.Contains(online, room.HostId) {
+			if !room.WaitingForHost {
+				return errHostUnchanged
+			}
+			room.WaitingForHost = false
+			return nil
+		}
+
+		for _, player := range room.Players {
+			if slices.Contains(candidates, player) {
+				room.HostId = player
+				room.WaitingForHost = false
+				return nil
+			}
+		}
+		if room.WaitingForHost {
+			// Still nobody to take over; the room already knows.
+			return errHostUnchanged
+		}
+		room.WaitingForHost = true
+		return nil
+	})
+	if errors.Is(err, errHostUnchanged) || errors.Is(err, store.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("host migration for room %s failed: %v", roomCode, err)
+		return
+	}
+
+	log.Printf("Room %s host: %s -> %s (waiting: %v)", roomCode, previous, room.HostId, room.WaitingForHost)
+	m.hub.Emit(roomCode, "host-changed", map[string]any{
+		"hostId":         room.HostId,
+		"hostName":       room.Profile(room.HostId).DisplayName,
+		"previousHostId": previous,
+		"waitingForHost": room.WaitingForHost,
+	})
+}
@@ -0,0 +1,135 @@
+package room
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"backend/internal/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	defaultQRSize = 256
+	maxQRSize     = 1024
+)
+
+// roomLink loads the room named in /room/{code}/... and returns its join URL.
+// It writes a 404 and returns false if the room does not exist or is closed.
+func (h *Handler) roomLink(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	code := strings.Split(r.URL.Path, "/")[2]
+	room, err := h.repo.GetRoom(r.Context(), code)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && room.State() == model.StateClosed) {
+		apierror.Write(w, http.StatusNotFound, "room_not_found", "Room not found")
+		return "", "", false
+	}
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return "", "", false
+	}
+	return room.Code, h.config.joinURL(room.Code), true
+}
+
+// JoinLinkHandler handles HTTP GET requests to /room/{code}/link.
+//
+// It returns the link players can open to join the room, together with the
+// paths of the QR code images for it:
+//
+//	GET /room/ABC123/link
+//
+//	Response:
+//	{
+//	  "roomCode": "ABC123",
+//	  "url": "https://spotiguess.example/?join=ABC123",
+//	  "qrPng": "/room/ABC123/qr.png",
+//	  "qrSvg": "/room/ABC123/qr.svg"
+//	}
+//
+// The link starts with JOIN_BASE_URL. Responds with 404 ("room_not_found")
+// if the room does not exist.
+func (h *Handler) JoinLinkHandler(w http.ResponseWriter, r *http.Request) {
+	code, url, ok := h.roomLink(w, r)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"roomCode": code,
+		"url":      url,
+		"qrPng":    "/room/" + code + "/qr.png",
+		"qrSvg":    "/room/" + code + "/qr.svg",
+	})
+}
+
+// QRCodeHandler handles HTTP GET requests to /room/{code}/qr.png and
+// /room/{code}/qr.svg.
+//
+// It renders the room's join link as a QR code. An optional "size" query
+// parameter sets the PNG width and height in pixels (default 256, max 1024);
+// the SVG scales freely.
+//
+//	GET /room/ABC123/qr.png?size=512
+func (h *Handler) QRCodeHandler(w http.ResponseWriter, r *http.Request) {
+	_, url, ok := h.roomLink(w, r)
+	if !ok {
+		return
+	}
+
+	qr, err := qrcode.New(url, qrcode.Medium)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if strings.HasSuffix(r.URL.Path, ".svg") {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(qrSVG(qr.Bitmap()))
+		return
+	}
+
+	size := defaultQRSize
+	if raw := r.URL.Query().Get("size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			apierror.Write(w, http.StatusBadRequest, "invalid_size", "size must be a positive number")
+			return
+		}
+		size = min(parsed, maxQRSize)
+	}
+	png, err := qr.PNG(size)
+	if err != nil {
+		apierror.Write(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(png)
+}
+
+// qrSVG draws a QR bitmap (including its quiet zone) as an SVG with one
+// horizontal run of dark modules per path segment.
+func qrSVG(bitmap [][]bool) []byte {
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	size := len(bitmap)
+	return fmt.Appendf(nil,
+		`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+			`<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="%s"/></svg>`,
+		size, size, size, size, path.String())
+}
@@ -0,0 +1,247 @@
+package room
+
+import (
+	"backend/internal/model"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	errPlayerBanned   = errors.New("player is banned from the room")
+	errPlayerNotFound = errors.New("player is not in the room")
+	errNotAllowed     = errors.New("only the player or the host may remove a player")
+	errNotInRoom      = errors.New("only players in the room may connect to it")
+	errRemoveHost     = errors.New("the host cannot kick or ban themselves")
+)
+
+// isBanned reports whether the Spotify user ID or any of the player IDs and
+// display names is banned from the room.
+func isBanned(room model.Room, spotifyID string, names ...string) bool {
+	for _, name := range names {
+		if slices.Contains(room.Banned, strings.ToLower(strings.TrimSpace(name))) {
+			return true
+		}
+	}
+	return spotifyID != "" && slices.Contains(room.BannedSpotifyIDs, spotifyID)
+}
+
+// AdmitPlayer is a ws.AdmitFunc. Only players in the room may open a
+// WebSocket connection to it: banned players get 403 ("player_banned"),
+// anyone else who is not in the room 403 ("not_in_room").
+func (h *Handler) AdmitPlayer(w http.ResponseWriter, r *http.Request, roomCode, playerID string) bool {
+	room, err := h.repo.GetRoom(r.Context(), roomCode)
+	if err == nil && isBanned(room, "", playerID) {
+		err = errPlayerBanned
+	}
+	if err == nil && !slices.Contains(room.Players, playerID) {
+		err = errNotInRoom
+	}
+	if err != nil {
+		writeRoomError(w, err)
+		return false
+	}
+	return true
+}
+
+// removePlayer removes the player from the room's Players, together with
+// their ready flag, profile and submitted songs. It returns errPlayerNotFound
+// if the player is not in the room.
+func removePlayer(room *model.Room, playerID string) error {
+	idx := slices.Index(room.Players, playerID)
+	if idx < 0 {
+		return errPlayerNotFound
+	}
+	room.Players = slices.Delete(room.Players, idx, idx+1)
+	room.Ready = slices.DeleteFunc(room.Ready, func(id string) bool { return id == playerID })
+	delete(room.Profiles, playerID)
+	delete(room.SpotifyIDs, playerID)
+	room.Submissions = slices.DeleteFunc(room.Submissions, func(track model.Track) bool {
+		return track.SubmittedBy == playerID
+	})
+	return nil
+}
+
+// dropPlayerData deletes the player's score and cached tracks for the room.
+func (h *Handler) dropPlayerData(ctx context.Context, roomCode, playerID string) {
+	if err := h.repo.DeleteScore(ctx, roomCode, playerID); err != nil {
+		log.Printf("Failed to delete score of %s: %v", playerID, err)
+	}
+	if err := h.repo.DeleteTracks(ctx, roomCode, playerID); err != nil {
+		log.Printf("Failed to delete tracks of %s: %v", playerID, err)
+	}
+}
+
+// LeaveRoomHandler handles HTTP POST requests to /leave-room.
+//
+// It expects a JSON payload in the following format:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "playerId": "spotify-user-456"
+//	}
+//
+// The player must send the access token they joined with in the
+// Authorization header ("Bearer <token>"). Alternatively the host may remove
+// any player by adding "hostId"; players who joined without a token can only
+// be removed by the host. Anyone else gets 403 ("not_allowed").
+//
+// The player is removed from the room, their score and cached tracks are
+// deleted and their WebSocket connections are closed. The rest of the room
+// receives:
+//
+//	{
+//	  "type": "player-left",
+//	  "data": { "playerId": "spotify-user-456", "displayName": "DJ Kasia" }
+//	}
+//
+// Responds with 404 ("room_not_found" or "player_not_found") if the room or
+// the player does not exist.
+func (h *Handler) LeaveRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.LeaveRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ownToken := false
+	authHeader := r.Header.Get("Authorization")
+	if token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer ")); strings.HasPrefix(authHeader, "Bearer ") && token != "" {
+		saved, err := h.repo.GetPlayerToken(r.Context(), request.PlayerID)
+		ownToken = err == nil && subtle.ConstantTimeCompare([]byte(saved), []byte(token)) == 1
+	}
+
+	var profile model.PlayerProfile
+	_, err := h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if !ownToken && (request.HostID == "" || request.HostID != room.HostId) {
+			return errNotAllowed
+		}
+		profile = room.Profile(request.PlayerID)
+		return removePlayer(room, request.PlayerID)
+	})
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	h.dropPlayerData(r.Context(), request.RoomCode, request.PlayerID)
+	h.hub.Disconnect(request.RoomCode, request.PlayerID)
+	h.hub.Emit(request.RoomCode, "player-left", map[string]string{
+		"playerId":    profile.ID,
+		"displayName": profile.DisplayName,
+	})
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "left",
+	})
+}
+
+// KickPlayerHandler handles HTTP POST requests to /kick-player.
+//
+// It expects a JSON payload in the following format:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "hostId": "spotify-user-456",
+//	  "playerId": "troll"
+//	}
+//
+// Only the host may kick players. The player is removed from the room, their
+// score and cached tracks are deleted, and the whole room (including the
+// kicked player) receives:
+//
+//	{
+//	  "type": "player-kicked",
+//	  "data": { "playerId": "troll", "displayName": "Troll", "banned": false }
+//	}
+//
+// after which the kicked player's WebSocket connections are closed. A kicked
+// player may join again; use /ban-player to prevent that. The host cannot
+// kick themselves and gets 400 ("cannot_remove_host").
+func (h *Handler) KickPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	h.removeByHost(w, r, false)
+}
+
+// BanPlayerHandler handles HTTP POST requests to /ban-player.
+//
+// It takes the same payload as /kick-player and kicks the player if they are
+// in the room. In addition the player ID, the display name and the player's
+// Spotify account, if they joined with one, are banned: joining the room again
+// fails with 403 ("player_banned"). A player that is not in the room can be banned in advance.
+// The broadcast "player-kicked" message has "banned" set to true.
+func (h *Handler) BanPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	h.removeByHost(w, r, true)
+}
+
+func (h *Handler) removeByHost(w http.ResponseWriter, r *http.Request, ban bool) {
+	var request model.KickPlayerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	if request.HostId != room.HostId {
+		http.Error(w, "Invalid HostId", http.StatusForbidden)
+		return
+	}
+
+	removed := false
+	var profile model.PlayerProfile
+	_, err = h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		// The host may have moved to another player since the room was read.
+		if strings.EqualFold(request.PlayerID, room.HostId) {
+			return errRemoveHost
+		}
+		spotifyID := room.SpotifyIDs[request.PlayerID]
+		profile = room.Profile(request.PlayerID)
+		err := removePlayer(room, request.PlayerID)
+		removed = err == nil
+		if !ban {
+			return err
+		}
+
+		for _, name := range []string{profile.ID, profile.DisplayName} {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !slices.Contains(room.Banned, name) {
+				room.Banned = append(room.Banned, name)
+			}
+		}
+		if spotifyID != "" && !slices.Contains(room.BannedSpotifyIDs, spotifyID) {
+			room.BannedSpotifyIDs = append(room.BannedSpotifyIDs, spotifyID)
+		}
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	if removed {
+		h.dropPlayerData(r.Context(), request.RoomCode, request.PlayerID)
+		h.hub.Emit(request.RoomCode, "player-kicked", map[string]any{
+			"playerId":    profile.ID,
+			"displayName": profile.DisplayName,
+			"banned":      ban,
+		})
+		h.hub.Disconnect(request.RoomCode, request.PlayerID)
+	}
+
+	status := "kicked"
+	if ban {
+		status = "banned"
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":   status,
+		"playerId": request.PlayerID,
+	})
+}
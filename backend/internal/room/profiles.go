@@ -0,0 +1,122 @@
+package room
+
+import (
+	"backend/internal/model"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minDisplayNameLength = 2
+	maxDisplayNameLength = 20
+)
+
+var (
+	errInvalidName    = errors.New("display name must be 2-20 characters without control characters")
+	errNameNotAllowed = errors.New("display name is not allowed")
+	errNameTaken      = errors.New("display name is already taken")
+)
+
+// newPlayerID returns a random player ID for players who did not bring one.
+func newPlayerID() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return "p-" + hex.EncodeToString(b)
+}
+
+// cleanDisplayName trims and collapses the whitespace in a display name and
+// checks its length and the profanity filter.
+func (h *Handler) cleanDisplayName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	length := utf8.RuneCountInString(name)
+	if length < minDisplayNameLength || length > maxDisplayNameLength {
+		return "", errInvalidName
+	}
+	for _, r := range name {
+		if !unicode.IsGraphic(r) {
+			return "", errInvalidName
+		}
+	}
+	if !h.filter.Allowed(name) {
+		return "", errNameNotAllowed
+	}
+	return name, nil
+}
+
+// nameTaken reports whether another player in the room already uses the
+// display name, ignoring case.
+func nameTaken(room model.Room, name, exceptID string) bool {
+	for _, player := range room.Players {
+		if player != exceptID && strings.EqualFold(room.Profile(player).DisplayName, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// RenamePlayerHandler handles HTTP POST requests to /rename-player.
+//
+// It expects a JSON payload in the following format:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "playerId": "p-3f9a0c12de",
+//	  "displayName": "DJ Kasia"
+//	}
+//
+// The display name is trimmed and must be 2-20 characters long, pass the
+// profanity filter and be unique in the room (ignoring case). The player ID
+// does not change. The new profile is broadcast to the room and returned:
+//
+//	{
+//	  "type": "player-renamed",
+//	  "data": { "playerId": "p-3f9a0c12de", "displayName": "DJ Kasia", "avatar": "..." }
+//	}
+//
+// Error codes: "invalid_display_name" (400), "display_name_not_allowed" (400),
+// "display_name_taken" (409), "room_not_found" (404) and "player_not_found" (404).
+func (h *Handler) RenamePlayerHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.RenamePlayerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, err := h.cleanDisplayName(request.DisplayName)
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	var profile model.PlayerProfile
+	_, err = h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if !slices.Contains(room.Players, request.PlayerID) {
+			return errPlayerNotFound
+		}
+		if nameTaken(*room, name, request.PlayerID) {
+			return errNameTaken
+		}
+
+		profile = room.Profile(request.PlayerID)
+		profile.DisplayName = name
+		if room.Profiles == nil {
+			room.Profiles = make(map[string]model.PlayerProfile)
+		}
+		room.Profiles[request.PlayerID] = profile
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	h.hub.Emit(request.RoomCode, "player-renamed", profile)
+	json.NewEncoder(w).Encode(profile)
+}
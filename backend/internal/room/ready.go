@@ -0,0 +1,84 @@
+package room
+
+import (
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+)
+
+// ReadyPayload is the data of a "ready" message sent by a client.
+type ReadyPayload struct {
+	Ready bool `json:"ready"`
+}
+
+// readyStatus splits the room's players into ready and not ready ones.
+func readyStatus(room model.Room) map[string][]string {
+	ready := []string{}
+	notReady := []string{}
+	for _, player := range room.Players {
+		if slices.Contains(room.Ready, player) {
+			ready = append(ready, player)
+		} else {
+			notReady = append(notReady, player)
+		}
+	}
+	return map[string][]string{
+		"ready":    ready,
+		"notReady": notReady,
+	}
+}
+
+// ReadyMessage handles "ready" WebSocket messages sent in the lobby:
+//
+//	{
+//	  "type": "ready",
+//	  "data": { "ready": true }
+//	}
+//
+// The player's ready flag is stored on the room and the whole room, including
+// the host, receives the updated ready check:
+//
+//	{
+//	  "type": "ready-changed",
+//	  "data": { "ready": ["player1"], "notReady": ["player2"] }
+//	}
+//
+// Messages from players who are not in the room, or sent outside the lobby,
+// are ignored. The ready check is cleared when a rematch goes back to the lobby.
+func (h *Handler) ReadyMessage(roomCode, playerID string, data json.RawMessage) {
+	var payload ReadyPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		log.Println("invalid ready message:", err)
+		return
+	}
+
+	room, err := h.repo.UpdateRoom(context.Background(), roomCode, func(room *model.Room) error {
+		if room.State() != model.StateLobby {
+			return errNotInLobby
+		}
+		if !slices.Contains(room.Players, playerID) {
+			return errPlayerNotFound
+		}
+
+		idx := slices.Index(room.Ready, playerID)
+		switch {
+		case payload.Ready && idx < 0:
+			room.Ready = append(room.Ready, playerID)
+		case !payload.Ready && idx >= 0:
+			room.Ready = slices.Delete(room.Ready, idx, idx+1)
+		}
+		return nil
+	})
+	if errors.Is(err, errNotInLobby) || errors.Is(err, errPlayerNotFound) {
+		return
+	}
+	if err != nil {
+		log.Printf("ready check for %s in room %s failed: %v", playerID, roomCode, err)
+		return
+	}
+
+	h.hub.Emit(roomCode, "ready-changed", readyStatus(room))
+}
@@ -0,0 +1,184 @@
+package room
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	errRoomFull        = errors.New("room is full")
+	errRoomLocked      = errors.New("room is locked")
+	errWrongPassword   = errors.New("wrong room password")
+	errNotInLobby      = errors.New("room is not in the lobby")
+	errBelowOccupancy  = errors.New("maxPlayers is below the number of players in the room")
+	errTitleNotAllowed = errors.New("room title is not allowed")
+)
+
+// hashPassword returns the bcrypt hash of a join password, or "" when the
+// password is empty (no password).
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// checkPassword reports whether the password opens the room.
+func checkPassword(room model.Room, password string) bool {
+	if room.PasswordHash == "" {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(room.PasswordHash), []byte(password)) == nil
+}
+
+// cleanTitle collapses the whitespace in the requested room title and checks
+// it against the profanity filter.
+func (h *Handler) cleanTitle(update *model.RoomSettingsUpdate) error {
+	if update.Title == nil {
+		return nil
+	}
+	title := strings.Join(strings.Fields(*update.Title), " ")
+	if !h.filter.Allowed(title) {
+		return errTitleNotAllowed
+	}
+	update.Title = &title
+	return nil
+}
+
+// applySettings applies a settings update to the room. passwordHash is the
+// already hashed new password and is only used when update.Password is set.
+func applySettings(room *model.Room, update model.RoomSettingsUpdate, passwordHash string) error {
+	if update.MaxPlayers != nil {
+		if *update.MaxPlayers < len(room.Players) {
+			return errBelowOccupancy
+		}
+		room.RoomSettings.MaxPlayers = *update.MaxPlayers
+	}
+	if update.Password != nil {
+		room.PasswordHash = passwordHash
+		room.RoomSettings.HasPassword = passwordHash != ""
+	}
+	if update.LateJoin != nil {
+		room.RoomSettings.LateJoin = *update.LateJoin
+	}
+	if update.Locked != nil {
+		room.RoomSettings.Locked = *update.Locked
+	}
+	if update.SongsPerPlayer != nil {
+		room.RoomSettings.SongsPerPlayer = *update.SongsPerPlayer
+	}
+	if update.Public != nil {
+		room.RoomSettings.Public = *update.Public
+	}
+	if update.Title != nil {
+		room.RoomSettings.Title = *update.Title
+	}
+	if update.GameMode != nil {
+		room.GameMode = *update.GameMode
+	}
+	return nil
+}
+
+// UpdateSettingsHandler handles HTTP POST requests to /room-settings.
+//
+// It expects a JSON payload in the following format, where every setting is optional:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "hostId": "spotify-user-456",
+//	  "settings": {
+//	    "maxPlayers": 6,
+//	    "password": "secret",
+//	    "lateJoin": true,
+//	    "locked": false,
+//	    "songsPerPlayer": 3,
+//	    "public": true,
+//	    "title": "Friday 90s night",
+//	    "gameMode": "playlist"
+//	  }
+//	}
+//
+// An empty "password" removes the password. "songsPerPlayer" (1-10, default 3)
+// limits the songs each player may submit with /submit-song. Public rooms are listed in the
+// lobby browser (/lobbies) under their title, which must be at most 40
+// characters and pass the profanity filter. "gameMode" announces the mode in
+// the lobby browser and is used by /start-game if it does not name one. Only the host may change the
+// settings, and only while the room is in the lobby.
+//
+// The new settings are broadcast to the room as "room-settings" and returned:
+//
+//	Response:
+//	{
+//	  "maxPlayers": 6,
+//	  "hasPassword": true,
+//	  "lateJoin": true,
+//	  "locked": false,
+//	  "songsPerPlayer": 3,
+//	  "public": true,
+//	  "title": "Friday 90s night"
+//	}
+//
+// Error codes: "invalid_settings" (400), "title_not_allowed" (400), "room_not_found" (404),
+// "invalid_state" (409) and "room_busy" (409). A wrong hostId gets 403.
+func (h *Handler) UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.RoomSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := request.Settings.Validate(); err != nil {
+		apierror.Write(w, http.StatusBadRequest, "invalid_settings", err.Error())
+		return
+	}
+	if err := h.cleanTitle(&request.Settings); err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	if request.HostId != room.HostId {
+		http.Error(w, "Invalid HostId", http.StatusForbidden)
+		return
+	}
+
+	passwordHash := ""
+	if request.Settings.Password != nil {
+		passwordHash, err = hashPassword(*request.Settings.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	room, err = h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if room.State() != model.StateLobby {
+			return errNotInLobby
+		}
+		return applySettings(room, request.Settings, passwordHash)
+	})
+	switch {
+	case errors.Is(err, errNotInLobby):
+		apierror.Write(w, http.StatusConflict, "invalid_state", "Room settings can only be changed in the lobby")
+		return
+	case errors.Is(err, errBelowOccupancy):
+		apierror.Write(w, http.StatusBadRequest, "invalid_settings", err.Error())
+		return
+	case err != nil:
+		writeRoomError(w, err)
+		return
+	}
+
+	h.hub.Emit(room.Code, "room-settings", room.RoomSettings)
+	json.NewEncoder(w).Encode(room.RoomSettings)
+}
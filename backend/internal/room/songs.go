@@ -0,0 +1,229 @@
+package room
+
+import (
+	"backend/internal/apierror"
+	"backend/internal/model"
+	"backend/internal/spotify"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	errSongLimit     = errors.New("song limit reached")
+	errSongSubmitted = errors.New("song was already submitted")
+	errSongNotFound  = errors.New("song is not in the pool")
+)
+
+// songPool describes the submitted songs without revealing them: the total
+// count, the count per player and the per-player limit.
+func songPool(room model.Room) map[string]any {
+	players := make(map[string]int)
+	for _, track := range room.Submissions {
+		players[track.SubmittedBy]++
+	}
+	return map[string]any{
+		"count":   len(room.Submissions),
+		"players": players,
+		"limit":   room.RoomSettings.SongLimit(),
+	}
+}
+
+// songsOf returns the songs the player submitted.
+func songsOf(room model.Room, playerID string) []model.Track {
+	songs := []model.Track{}
+	for _, track := range room.Submissions {
+		if track.SubmittedBy == playerID {
+			songs = append(songs, track)
+		}
+	}
+	return songs
+}
+
+// checkSubmission reports why the player cannot add the track to the pool.
+func checkSubmission(room model.Room, playerID, trackID string) error {
+	if room.State() != model.StateLobby {
+		return errNotInLobby
+	}
+	if !slices.Contains(room.Players, playerID) {
+		return errPlayerNotFound
+	}
+	if slices.ContainsFunc(room.Submissions, func(track model.Track) bool { return track.ID == trackID }) {
+		return errSongSubmitted
+	}
+	if len(songsOf(room, playerID)) >= room.RoomSettings.SongLimit() {
+		return errSongLimit
+	}
+	return nil
+}
+
+// SearchTracksHandler handles HTTP GET requests to /room/{code}/search.
+//
+// It searches Spotify for tracks on behalf of the players, so players without
+// a Spotify account (or without Premium) can pick songs too:
+//
+//	GET /room/ABC123/search?q=bohemian
+//
+//	Response:
+//	[
+//	  { "id": "3z8h0TU7ReDPLIbEnYhWZb", "name": "Bohemian Rhapsody", "artists": "Queen", "image": "https://..." }
+//	]
+//
+// The search uses the host's Spotify token.
+func (h *Handler) SearchTracksHandler(w http.ResponseWriter, r *http.Request) {
+	code := strings.Split(r.URL.Path, "/")[2]
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.repo.GetRoom(r.Context(), code)
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	token, err := h.repo.GetPlayerToken(r.Context(), room.HostId)
+	if err != nil {
+		apierror.Write(w, http.StatusServiceUnavailable, "spotify_unavailable", "The host's Spotify session has expired")
+		return
+	}
+
+	results, err := h.spotify.Search(r.Context(), token, query, "track", room.Market)
+	if errors.Is(err, spotify.ErrUnauthorized) {
+		apierror.Write(w, http.StatusServiceUnavailable, "spotify_unavailable", "The host's Spotify session has expired")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Spotify search failed", http.StatusBadGateway)
+		return
+	}
+	json.NewEncoder(w).Encode(results)
+}
+
+// SubmitSongHandler handles HTTP POST requests to /submit-song.
+//
+// It expects a JSON payload in the following format:
+//
+//	{
+//	  "roomCode": "ABC123",
+//	  "playerId": "p-3f9a0c12de",
+//	  "trackId": "3z8h0TU7ReDPLIbEnYhWZb"
+//	}
+//
+// Players pick songs in the lobby, up to the room's songsPerPlayer (3 by
+// default). The track is looked up on Spotify with the host's token, so
+// submitting needs no Spotify account. In "players" mode the submitted songs
+// become the quiz pool instead of the players' recently played tracks, and
+// each question names the player who picked its song in "submittedBy".
+//
+// The songs stay secret: the room only receives the size of the pool,
+//
+//	{
+//	  "type": "song-pool",
+//	  "data": { "count": 5, "players": { "p-3f9a0c12de": 2, "host": 3 }, "limit": 3 }
+//	}
+//
+// and the response lists the songs of the submitting player:
+//
+//	{ "songs": [{ "id": "...", "name": "Bohemian Rhapsody", "artists": ["Queen"], ... }] }
+//
+// Error codes: "invalid_state" (409) outside the lobby, "player_not_found"
+// (404), "song_already_submitted" (409), "song_limit_reached" (409),
+// "track_not_found" (404), "track_not_playable" (422, not available in the
+// host's country) and "spotify_unavailable" (503).
+func (h *Handler) SubmitSongHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.SongRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.TrackID == "" {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.repo.GetRoom(r.Context(), request.RoomCode)
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+	if err := checkSubmission(room, request.PlayerID, request.TrackID); err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	token, err := h.repo.GetPlayerToken(r.Context(), room.HostId)
+	if err != nil {
+		apierror.Write(w, http.StatusServiceUnavailable, "spotify_unavailable", "The host's Spotify session has expired")
+		return
+	}
+	track, err := h.spotify.Track(r.Context(), token, request.TrackID, room.Market)
+	if errors.Is(err, spotify.ErrUnauthorized) {
+		apierror.Write(w, http.StatusServiceUnavailable, "spotify_unavailable", "The host's Spotify session has expired")
+		return
+	}
+	if errors.Is(err, spotify.ErrNotPlayable) {
+		apierror.Write(w, http.StatusUnprocessableEntity, "track_not_playable", "This track cannot be played in the host's country")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to fetch track %s: %v", request.TrackID, err)
+		apierror.Write(w, http.StatusNotFound, "track_not_found", "Track not found on Spotify")
+		return
+	}
+	track.SubmittedBy = request.PlayerID
+
+	room, err = h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if err := checkSubmission(*room, request.PlayerID, track.ID); err != nil {
+			return err
+		}
+		room.Submissions = append(room.Submissions, track)
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	h.hub.Emit(room.Code, "song-pool", songPool(room))
+	json.NewEncoder(w).Encode(map[string]any{
+		"songs": songsOf(room, request.PlayerID),
+	})
+}
+
+// RemoveSongHandler handles HTTP POST requests to /remove-song.
+//
+// It takes the same payload as /submit-song and takes the player's song out
+// of the pool again. The room receives the new "song-pool" and the response
+// lists the player's remaining songs. Only the player who submitted a song
+// can remove it; other songs give 404 ("song_not_found").
+func (h *Handler) RemoveSongHandler(w http.ResponseWriter, r *http.Request) {
+	var request model.SongRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	room, err := h.repo.UpdateRoom(r.Context(), request.RoomCode, func(room *model.Room) error {
+		if room.State() != model.StateLobby {
+			return errNotInLobby
+		}
+		idx := slices.IndexFunc(room.Submissions, func(track model.Track) bool {
+			return track.ID == request.TrackID && track.SubmittedBy == request.PlayerID
+		})
+		if idx < 0 {
+			return errSongNotFound
+		}
+		room.Submissions = slices.Delete(room.Submissions, idx, idx+1)
+		return nil
+	})
+	if err != nil {
+		writeRoomError(w, err)
+		return
+	}
+
+	h.hub.Emit(room.Code, "song-pool", songPool(room))
+	json.NewEncoder(w).Encode(map[string]any{
+		"songs": songsOf(room, request.PlayerID),
+	})
+}
@@ -0,0 +1,275 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL     = "https://api.spotify.com/v1"
+	defaultAccountsURL = "https://accounts.spotify.com"
+
+	// maxRetries is how often a rate-limited request is retried.
+	maxRetries = 3
+	// maxRetryWait is the longest Retry-After the client waits for. Longer
+	// waits fail right away with ErrRateLimited.
+	maxRetryWait = 30 * time.Second
+	// requestTimeout bounds every single HTTP request.
+	requestTimeout = 15 * time.Second
+)
+
+var (
+	// ErrUnauthorized is returned for 401 responses: the token is invalid or expired.
+	ErrUnauthorized = errors.New("spotify: unauthorized")
+	// ErrForbidden is returned for 403 responses, e.g. a missing scope or a
+	// feature that needs Spotify Premium.
+	ErrForbidden = errors.New("spotify: forbidden")
+	// ErrNotFound is returned for 404 responses.
+	ErrNotFound = errors.New("spotify: not found")
+	// ErrRateLimited is returned when Spotify keeps answering 429 after all retries.
+	ErrRateLimited = errors.New("spotify: rate limited")
+	// ErrNotModified is returned for 304 responses to conditional requests:
+	// the resource still has the ETag the caller sent.
+	ErrNotModified = errors.New("spotify: not modified")
+)
+
+// APIError is a non-2xx response from Spotify. It matches ErrUnauthorized,
+// ErrForbidden, ErrNotFound and ErrRateLimited with errors.Is.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("spotify: status %d: %s", e.Status, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	switch e.Status {
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// Config holds the Spotify endpoints and app credentials.
+type Config struct {
+	// BaseURL is the Web API root, https://api.spotify.com/v1 by default.
+	BaseURL string
+	// AccountsURL is the OAuth server, https://accounts.spotify.com by default.
+	AccountsURL  string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+}
+
+// ConfigFromEnv reads the configuration from SPOTIFY_CLIENT_ID,
+// SPOTIFY_CLIENT_SECRET and SPOTIFY_REDIRECT_URI. SPOTIFY_API_URL and
+// SPOTIFY_ACCOUNTS_URL override the endpoints, e.g. for a mock server.
+func ConfigFromEnv() Config {
+	config := Config{
+		BaseURL:      os.Getenv("SPOTIFY_API_URL"),
+		AccountsURL:  os.Getenv("SPOTIFY_ACCOUNTS_URL"),
+		ClientID:     os.Getenv("SPOTIFY_CLIENT_ID"),
+		ClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		RedirectURI:  os.Getenv("SPOTIFY_REDIRECT_URI"),
+	}
+	if config.BaseURL == "" {
+		config.BaseURL = defaultBaseURL
+	}
+	if config.AccountsURL == "" {
+		config.AccountsURL = defaultAccountsURL
+	}
+	return config
+}
+
+// Client calls the Spotify Web API. Every call takes the user's access token,
+// so one Client serves all players. Rate-limited requests are retried after
+// the Retry-After delay.
+type Client struct {
+	config Config
+	http   *http.Client
+}
+
+// NewClient returns a Client for the given configuration.
+func NewClient(config Config) *Client {
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	config.AccountsURL = strings.TrimSuffix(config.AccountsURL, "/")
+	return &Client{
+		config: config,
+		http:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// url resolves a path like "/me" against the base URL. Absolute URLs, such
+// as the "next" links of paging objects, are used as they are.
+func (c *Client) url(path string, query url.Values) string {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		path = c.config.BaseURL + path
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// do sends the request built by newRequest and decodes a successful JSON
+// response into v. On 429 it waits for Retry-After and sends a new request,
+// up to maxRetries times.
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error), v any) error {
+	_, err := c.send(ctx, newRequest, v)
+	return err
+}
+
+// send is do, also returning the response headers. A 304 response gives
+// ErrNotModified.
+func (c *Client) send(ctx context.Context, newRequest func() (*http.Request, error), v any) (http.Header, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.http.Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			wait := retryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			if wait > maxRetryWait {
+				return nil, &APIError{Status: http.StatusTooManyRequests, Message: "retry after " + wait.String()}
+			}
+			log.Printf("Spotify rate limit hit, retrying %s in %s", req.URL.Path, wait)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotModified {
+			return resp.Header, ErrNotModified
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return resp.Header, responseError(resp)
+		}
+		if v == nil || resp.StatusCode == http.StatusNoContent {
+			return resp.Header, nil
+		}
+		return resp.Header, json.NewDecoder(resp.Body).Decode(v)
+	}
+}
+
+// retryAfter parses the Retry-After header (in seconds), defaulting to one second.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 1 {
+		return time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// responseError reads the error object of a failed response. The Web API
+// sends {"error": {"status": 401, "message": "..."}}, the accounts service
+// {"error": "invalid_grant", "error_description": "..."}.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	apiErr := &APIError{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var webAPI struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	var accounts struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	switch {
+	case json.Unmarshal(body, &webAPI) == nil && webAPI.Error.Message != "":
+		apiErr.Message = webAPI.Error.Message
+	case json.Unmarshal(body, &accounts) == nil && accounts.Error != "":
+		apiErr.Message = strings.TrimSpace(accounts.Error + " " + accounts.Description)
+	}
+	return apiErr
+}
+
+// get sends an authorized GET request to the Web API.
+func (c *Client) get(ctx context.Context, token, path string, query url.Values, v any) error {
+	_, err := c.getConditional(ctx, token, path, query, "", v)
+	return err
+}
+
+// getConditional is get with an If-None-Match header, unless etag is empty.
+// It returns the ETag of the response, or ErrNotModified if the resource
+// still has the given ETag.
+func (c *Client) getConditional(ctx context.Context, token, path string, query url.Values, etag string, v any) (string, error) {
+	header, err := c.send(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, c.url(path, query), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept", "application/json")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return req, nil
+	}, v)
+	return header.Get("ETag"), err
+}
+
+// Page is a Spotify paging object.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next"`
+	Total int    `json:"total"`
+}
+
+// Paginate iterates over the items of a paged endpoint, following the "next"
+// links until the last page or until the caller stops:
+//
+//	for track, err := range spotify.Paginate[playlistItem](ctx, client, token, "/playlists/"+id+"/tracks", nil) {
+//		if err != nil { ... }
+//	}
+//
+// A failed request is yielded once as an error with a zero item and ends the iteration.
+func Paginate[T any](ctx context.Context, c *Client, token, path string, query url.Values) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		next := c.url(path, query)
+		for next != "" {
+			var page Page[T]
+			if err := c.get(ctx, token, next, nil, &page); err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			next = page.Next
+		}
+	}
+}
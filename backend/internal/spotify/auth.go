@@ -0,0 +1,48 @@
+package spotify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Token is the response of the accounts service token endpoint. RefreshToken
+// is only set when exchanging an authorization code.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// ExchangeCode exchanges an OAuth authorization code for tokens, using the
+// configured redirect URI.
+func (c *Client) ExchangeCode(ctx context.Context, code string) (Token, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.config.RedirectURI},
+	})
+}
+
+// RefreshToken returns a new access token for the refresh token.
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (Token, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+}
+
+func (c *Client) requestToken(ctx context.Context, form url.Values) (Token, error) {
+	var token Token
+	err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, c.config.AccountsURL+"/api/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.config.ClientID, c.config.ClientSecret)
+		return req, nil
+	}, &token)
+	return token, err
+}
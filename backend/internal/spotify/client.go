@@ -2,247 +2,495 @@ package spotify
 
 import (
 	"backend/internal/model"
+	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
+	"iter"
 	"net/http"
 	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 )
 
-type recentlyPlayedResponse struct {
-	Items []struct {
-		Track struct {
-			ID         string `json:"id"`
-			Name       string `json:"name"`
-			DurationMs int    `json:"duration_ms"`
-			Artists    []struct {
-				Name string `json:"name"`
-			} `json:"artists"`
-		} `json:"track"`
-	} `json:"items"`
-}
-
-type recommendationResponse struct {
-	Tracks []struct {
+type image struct {
+	URL string `json:"url"`
+}
+
+// apiTrack is the track object of the Web API.
+type apiTrack struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	DurationMs int    `json:"duration_ms"`
+	Explicit   bool   `json:"explicit"`
+	// Type is "track", or "episode" for podcast episodes in playlists.
+	Type    string `json:"type"`
+	IsLocal bool   `json:"is_local"`
+	// IsPlayable is only set when the request names a market.
+	IsPlayable *bool `json:"is_playable"`
+	Artists    []struct {
 		Name string `json:"name"`
-		// (opcjonalnie)
-		Artists []struct {
-			Name string `json:"name"`
-		} `json:"artists"`
-	} `json:"tracks"`
+	} `json:"artists"`
+	Album struct {
+		Images []image `json:"images"`
+	} `json:"album"`
+}
+
+func (t apiTrack) model() model.Track {
+	track := model.Track{ID: t.ID, Name: t.Name, Duration: t.DurationMs, Explicit: t.Explicit}
+	for _, artist := range t.Artists {
+		track.Artists = append(track.Artists, artist.Name)
+	}
+	return track
+}
+
+// playable reports whether the track can be played: it is a Spotify track
+// (not a local file or an episode) and, if the market was checked, available there.
+func (t apiTrack) playable() bool {
+	return t.ID != "" && !t.IsLocal && (t.Type == "" || t.Type == "track") && (t.IsPlayable == nil || *t.IsPlayable)
+}
+
+// marketQuery returns the query with the market set, unless market is empty.
+func marketQuery(query url.Values, market string) url.Values {
+	if market != "" {
+		query.Set("market", market)
+	}
+	return query
+}
+
+// firstImage returns the URL of the first (largest) image, or "".
+func firstImage(images []image) string {
+	if len(images) == 0 {
+		return ""
+	}
+	return images[0].URL
 }
 
-// FetchRecentTracks retrieves the most recently played tracks for a Spotify user,
-// using the provided OAuth access token.
+// RecentTracks retrieves the most recently played tracks of the user that
+// owns the token from:
 //
-// It performs a GET request to the Spotify Web API endpoint:
+//	GET /me/player/recently-played?limit=25
 //
-//	https://api.spotify.com/v1/me/player/recently-played?limit=25
+// Only basic metadata is extracted: ID, name, artists and duration.
+func (c *Client) RecentTracks(ctx context.Context, token string) ([]model.Track, error) {
+	var resp struct {
+		Items []struct {
+			Track apiTrack `json:"track"`
+		} `json:"items"`
+	}
+	if err := c.get(ctx, token, "/me/player/recently-played", url.Values{"limit": {"25"}}, &resp); err != nil {
+		return nil, err
+	}
+
+	var tracks []model.Track
+	for _, item := range resp.Items {
+		if item.Track.playable() {
+			tracks = append(tracks, item.Track.model())
+		}
+	}
+	return tracks, nil
+}
+
+// maxUserTracks caps how many top or liked tracks are fetched per user.
+const maxUserTracks = 100
+
+// TopTracks retrieves up to maxUserTracks of the user's top tracks over the
+// given time range ("short_term", "medium_term" or "long_term"):
 //
-// The function parses the response, extracts relevant fields from each track,
-// and returns a slice of Track structs containing:
-//   - ID: Spotify track ID
-//   - Name: track title
-//   - Artists: list of artist names
+//	GET /me/top/tracks?time_range=medium_term&limit=50
 //
-// Only basic metadata is extracted — the preview URL is ignored here.
-// The function returns an error if the HTTP request or JSON decoding fails.
+// It needs the user-top-read scope.
+func (c *Client) TopTracks(ctx context.Context, token, timeRange string) ([]model.Track, error) {
+	items := Paginate[apiTrack](ctx, c, token, "/me/top/tracks", url.Values{
+		"time_range": {timeRange},
+		"limit":      {"50"},
+	})
+	return collectTracks(items, func(t apiTrack) *apiTrack { return &t })
+}
+
+// SavedTracks retrieves up to maxUserTracks of the user's liked songs
+// playable in the market, the most recently saved first:
 //
-// Parameters:
-//   - token: a valid Spotify OAuth access token
+//	GET /me/tracks?limit=50&market=PL
 //
-// Returns:
-//   - []Track: a slice of tracks the user recently listened to
-//   - error: if any step fails
-func FetchRecentTracks(token string) ([]model.Track, error) {
-	url := "https://api.spotify.com/v1/me/player/recently-played?limit=25"
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// It needs the user-library-read scope.
+func (c *Client) SavedTracks(ctx context.Context, token, market string) ([]model.Track, error) {
+	type savedTrack struct {
+		Track *apiTrack `json:"track"`
 	}
-	body, _ := io.ReadAll(resp.Body)
-
-	var apiResp recentlyPlayedResponse
+	items := Paginate[savedTrack](ctx, c, token, "/me/tracks", marketQuery(url.Values{"limit": {"50"}}, market))
+	return collectTracks(items, func(item savedTrack) *apiTrack { return item.Track })
+}
 
-	defer resp.Body.Close()
-	err = json.Unmarshal(body, &apiResp)
-	if err != nil {
-		return nil, err
+// UserTracks retrieves the user's tracks from the given source: "top" (top
+// tracks over timeRange), "liked" or "recent" (recently played, the default).
+// Only liked songs can be filtered by market; use PlayableTracks for the others.
+func (c *Client) UserTracks(ctx context.Context, token, source, timeRange, market string) ([]model.Track, error) {
+	switch source {
+	case model.SourceTop:
+		return c.TopTracks(ctx, token, timeRange)
+	case model.SourceLiked:
+		return c.SavedTracks(ctx, token, market)
 	}
-	var tracks []model.Track
-	for _, item := range apiResp.Items {
-		trackData := item.Track
-		var artistNames []string
-		for _, artist := range trackData.Artists {
-			artistNames = append(artistNames, artist.Name)
+	return c.RecentTracks(ctx, token)
+}
 
+// collectTracks reads up to maxUserTracks tracks from a paged endpoint.
+// Items without a playable track, such as local files, are skipped.
+func collectTracks[T any](items iter.Seq2[T, error], track func(T) *apiTrack) ([]model.Track, error) {
+	var tracks []model.Track
+	for item, err := range items {
+		if err != nil {
+			return nil, err
+		}
+		t := track(item)
+		if t == nil || !t.playable() {
+			continue
+		}
+		tracks = append(tracks, t.model())
+		if len(tracks) >= maxUserTracks {
+			break
 		}
-		tracks = append(tracks, model.Track{
-			ID:       trackData.ID,
-			Name:     trackData.Name,
-			Artists:  artistNames,
-			Duration: trackData.DurationMs,
-		})
 	}
 	return tracks, nil
 }
 
-func SearchSpotifyHandler(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("q")
-	searchType := r.URL.Query().Get("type")
-	userID := r.URL.Query().Get("userId")
+// Profile is the subset of the Spotify user profile used by the game.
+type Profile struct {
+	ID          string  `json:"id"`
+	DisplayName string  `json:"display_name"`
+	Country     string  `json:"country"`
+	Images      []image `json:"images"`
+}
 
-	if query == "" || (searchType != "playlist" && searchType != "artist") || userID == "" {
-		http.Error(w, "Missing or invalid query parameters", http.StatusBadRequest)
-		return
+// Me retrieves the profile of the user that owns the token from GET /me.
+func (c *Client) Me(ctx context.Context, token string) (Profile, error) {
+	var profile Profile
+	err := c.get(ctx, token, "/me", nil, &profile)
+	return profile, err
+}
+
+// ErrNotPlayable is returned by Track for tracks that cannot be played in the market.
+var ErrNotPlayable = errors.New("spotify: track is not playable")
+
+// Track retrieves a single track from GET /tracks/{id}. An unknown ID gives
+// an error matching ErrNotFound (or a 400 APIError for malformed IDs), a
+// track that cannot be played in the market ErrNotPlayable.
+func (c *Client) Track(ctx context.Context, token, trackID, market string) (model.Track, error) {
+	var track apiTrack
+	if err := c.get(ctx, token, "/tracks/"+url.PathEscape(trackID), marketQuery(url.Values{}, market), &track); err != nil {
+		return model.Track{}, err
 	}
+	if !track.playable() {
+		return model.Track{}, ErrNotPlayable
+	}
+	return track.model(), nil
+}
 
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-		http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
-		return
+// PlayableTracks returns the tracks that can be played in the market, in
+// their original order. They are checked 50 at a time with:
+//
+//	GET /tracks?ids=...&market=PL
+//
+// An empty market checks the market of the token's user.
+func (c *Client) PlayableTracks(ctx context.Context, token string, tracks []model.Track, market string) ([]model.Track, error) {
+	if market == "" {
+		market = "from_token"
 	}
-	token := strings.TrimPrefix(authHeader, "Bearer ")
-	token = strings.TrimSpace(token)
+	var playable []model.Track
+	for batch := range slices.Chunk(tracks, 50) {
+		ids := make([]string, len(batch))
+		for i, track := range batch {
+			ids[i] = track.ID
+		}
+		var resp struct {
+			// Unknown IDs are null.
+			Tracks []*apiTrack `json:"tracks"`
+		}
+		query := url.Values{"ids": {strings.Join(ids, ",")}, "market": {market}}
+		if err := c.get(ctx, token, "/tracks", query, &resp); err != nil {
+			return nil, err
+		}
+		for i, track := range resp.Tracks {
+			if i < len(batch) && track != nil && track.playable() {
+				playable = append(playable, batch[i])
+			}
+		}
+	}
+	return playable, nil
+}
 
-	results, err := SearchSpotify(query, searchType, token)
-	if err != nil {
-		http.Error(w, "Spotify search failed", http.StatusBadGateway)
-		return
+// PlaylistTracks iterates over all tracks of a playlist. Local files,
+// episodes, removed tracks and tracks not playable in the market are skipped.
+func (c *Client) PlaylistTracks(ctx context.Context, token, playlistID, market string) iter.Seq2[model.Track, error] {
+	type playlistItem struct {
+		Track *apiTrack `json:"track"`
 	}
+	items := Paginate[playlistItem](ctx, c, token, "/playlists/"+url.PathEscape(playlistID)+"/tracks", marketQuery(url.Values{"limit": {"100"}}, market))
+	return func(yield func(model.Track, error) bool) {
+		for item, err := range items {
+			if err != nil {
+				yield(model.Track{}, err)
+				return
+			}
+			if item.Track == nil || !item.Track.playable() {
+				continue
+			}
+			if !yield(item.Track.model(), nil) {
+				return
+			}
+		}
+	}
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(results)
+// PlaylistSnapshot returns the snapshot ID of a playlist, which changes
+// whenever its tracks change:
+//
+//	GET /playlists/{id}?fields=snapshot_id
+func (c *Client) PlaylistSnapshot(ctx context.Context, token, playlistID string) (string, error) {
+	var playlist struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	err := c.get(ctx, token, "/playlists/"+url.PathEscape(playlistID), url.Values{"fields": {"snapshot_id"}}, &playlist)
+	return playlist.SnapshotID, err
 }
 
-func SearchSpotify(query string, searchType string, token string) ([]map[string]string, error) {
-	base := "https://api.spotify.com/v1/search"
-	params := url.Values{}
-	params.Set("q", query)
-	params.Set("type", searchType)
-	params.Set("limit", "10")
+// Album is a simplified album object.
+type Album struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
 
-	req, err := http.NewRequest("GET", base+"?"+params.Encode(), nil)
-	if err != nil {
-		return nil, err
-	}
+// ArtistAlbums iterates over the albums and singles of an artist available
+// in the market.
+func (c *Client) ArtistAlbums(ctx context.Context, token, artistID, market string) iter.Seq2[Album, error] {
+	return Paginate[Album](ctx, c, token, "/artists/"+url.PathEscape(artistID)+"/albums", artistAlbumsQuery(market))
+}
 
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/json")
+// ArtistAlbumsETag returns the ETag of the first page of ArtistAlbums, or
+// ErrNotModified if it still matches etag. A new release changes it.
+func (c *Client) ArtistAlbumsETag(ctx context.Context, token, artistID, market, etag string) (string, error) {
+	return c.getConditional(ctx, token, "/artists/"+url.PathEscape(artistID)+"/albums", artistAlbumsQuery(market), etag, nil)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+func artistAlbumsQuery(market string) url.Values {
+	return marketQuery(url.Values{
+		"include_groups": {"album,single"},
+		"limit":          {"50"},
+	}, market)
+}
+
+// AlbumTracks iterates over the tracks of an album that are playable in the market.
+func (c *Client) AlbumTracks(ctx context.Context, token, albumID, market string) iter.Seq2[model.Track, error] {
+	items := Paginate[apiTrack](ctx, c, token, "/albums/"+url.PathEscape(albumID)+"/tracks", marketQuery(url.Values{"limit": {"50"}}, market))
+	return func(yield func(model.Track, error) bool) {
+		for item, err := range items {
+			if err != nil {
+				yield(model.Track{}, err)
+				return
+			}
+			if !item.playable() {
+				continue
+			}
+			if !yield(item.model(), nil) {
+				return
+			}
+		}
 	}
-	defer resp.Body.Close()
+}
+
+// SearchResult is a playlist, artist or track found by Search.
+type SearchResult struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Owner   string `json:"owner,omitempty"`
+	Artists string `json:"artists,omitempty"`
+	Image   string `json:"image"`
+}
 
-	body, _ := io.ReadAll(resp.Body)
+type searchResponse struct {
+	// Spotify returns null for playlists that are no longer available.
+	Playlists Page[*struct {
+		ID     string  `json:"id"`
+		Name   string  `json:"name"`
+		Images []image `json:"images"`
+		Owner  struct {
+			DisplayName string `json:"display_name"`
+		} `json:"owner"`
+	}] `json:"playlists"`
+	Artists Page[struct {
+		ID     string  `json:"id"`
+		Name   string  `json:"name"`
+		Images []image `json:"images"`
+	}] `json:"artists"`
+	Albums Page[struct {
+		ID      string  `json:"id"`
+		Name    string  `json:"name"`
+		Images  []image `json:"images"`
+		Artists []struct {
+			Name string `json:"name"`
+		} `json:"artists"`
+	}] `json:"albums"`
+	Tracks Page[apiTrack] `json:"tracks"`
+}
 
-	var data map[string]any
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, err
+// search calls GET /search for one type of item. An empty market uses the
+// market of the token's user.
+func (c *Client) search(ctx context.Context, token, query, searchType string, limit int, market string) (searchResponse, error) {
+	params := url.Values{
+		"q":     {query},
+		"type":  {searchType},
+		"limit": {strconv.Itoa(limit)},
 	}
+	if market != "" {
+		params.Set("market", market)
+	}
+	var resp searchResponse
+	err := c.get(ctx, token, "/search", params, &resp)
+	return resp, err
+}
 
-	var results []map[string]string
-
-	if searchType == "playlist" {
-		playlistsRaw, ok := data["playlists"]
-		if !ok || playlistsRaw == nil {
-			return results, nil
-		}
+// Search returns the first 10 playlists, artists, albums or tracks matching
+// the query. searchType is "playlist", "artist", "album" or "track". An empty
+// market uses the market of the token's user.
+func (c *Client) Search(ctx context.Context, token, query, searchType, market string) ([]SearchResult, error) {
+	resp, err := c.search(ctx, token, query, searchType, 10, market)
+	if err != nil {
+		return nil, err
+	}
 
-		playlistsMap, ok := playlistsRaw.(map[string]any)
-		if !ok {
-			return results, nil
+	results := []SearchResult{}
+	switch searchType {
+	case "playlist":
+		for _, p := range resp.Playlists.Items {
+			if p == nil {
+				continue
+			}
+			results = append(results, SearchResult{
+				ID:    p.ID,
+				Name:  p.Name,
+				Owner: p.Owner.DisplayName,
+				Image: firstImage(p.Images),
+			})
 		}
-
-		itemsRaw, ok := playlistsMap["items"]
-		if !ok || itemsRaw == nil {
-			return results, nil
+	case "artist":
+		for _, a := range resp.Artists.Items {
+			results = append(results, SearchResult{ID: a.ID, Name: a.Name, Image: firstImage(a.Images)})
 		}
-
-		items, ok := itemsRaw.([]any)
-		if !ok {
-			return results, nil
+	case "album":
+		for _, a := range resp.Albums.Items {
+			var artists []string
+			for _, artist := range a.Artists {
+				artists = append(artists, artist.Name)
+			}
+			results = append(results, SearchResult{
+				ID:      a.ID,
+				Name:    a.Name,
+				Artists: strings.Join(artists, ", "),
+				Image:   firstImage(a.Images),
+			})
 		}
-
-		for _, raw := range items {
-			p, ok := raw.(map[string]any)
-			if !ok {
+	case "track":
+		for _, t := range resp.Tracks.Items {
+			if !t.playable() {
 				continue
 			}
+			results = append(results, SearchResult{
+				ID:      t.ID,
+				Name:    t.Name,
+				Artists: strings.Join(t.model().Artists, ", "),
+				Image:   firstImage(t.Album.Images),
+			})
+		}
+	}
+	return results, nil
+}
 
-			id, _ := p["id"].(string)
-			name, _ := p["name"].(string)
+// maxSearchTracks caps how many tracks GenreTracks collects.
+const maxSearchTracks = 100
 
-			owner := ""
-			if ownerMap, ok := p["owner"].(map[string]any); ok {
-				owner, _ = ownerMap["display_name"].(string)
-			}
+// GenreTracks searches up to maxSearchTracks tracks of a genre playable in
+// the market, released between fromYear and toYear (both 0 for any year):
+//
+//	GET /search?q=genre:"indie rock" year:1990-1999&type=track&limit=50&market=PL
+func (c *Client) GenreTracks(ctx context.Context, token, genre string, fromYear, toYear int, market string) ([]model.Track, error) {
+	query := "genre:" + strconv.Quote(genre)
+	if fromYear != 0 {
+		query += fmt.Sprintf(" year:%d-%d", fromYear, toYear)
+	}
+	resp, err := c.search(ctx, token, query, "track", 50, market)
+	if err != nil {
+		return nil, err
+	}
 
-			imageURL := ""
-			if images, ok := p["images"].([]any); ok && len(images) > 0 {
-				if img, ok := images[0].(map[string]any); ok {
-					imageURL, _ = img["url"].(string)
-				}
+	var tracks []model.Track
+	for page := resp.Tracks; ; {
+		for _, t := range page.Items {
+			if t.playable() && len(tracks) < maxSearchTracks {
+				tracks = append(tracks, t.model())
 			}
-
-			results = append(results, map[string]string{
-				"id":    id,
-				"name":  name,
-				"owner": owner,
-				"image": imageURL,
-			})
 		}
-	}
-
-	if searchType == "artist" {
-		artistsRaw, ok := data["artists"]
-		if !ok || artistsRaw == nil {
-			return results, nil
+		if page.Next == "" || len(tracks) >= maxSearchTracks {
+			return tracks, nil
 		}
-
-		artistsMap, ok := artistsRaw.(map[string]any)
-		if !ok {
-			return results, nil
+		var next searchResponse
+		if err := c.get(ctx, token, page.Next, nil, &next); err != nil {
+			return nil, err
 		}
+		page = next.Tracks
+	}
+}
 
-		itemsRaw, ok := artistsMap["items"]
-		if !ok || itemsRaw == nil {
-			return results, nil
-		}
+// Handler serves the Spotify proxy endpoints.
+type Handler struct {
+	client *Client
+}
 
-		items, ok := itemsRaw.([]any)
-		if !ok {
-			return results, nil
-		}
+// NewHandler returns a Handler using the given client.
+func NewHandler(client *Client) *Handler {
+	return &Handler{client: client}
+}
 
-		for _, raw := range items {
-			a, ok := raw.(map[string]any)
-			if !ok {
-				continue
-			}
+// SearchSpotifyHandler handles HTTP GET requests to /spotify/search.
+//
+//	GET /spotify/search?q=queen&type=artist&userId=spotify-user-456
+//	Authorization: Bearer <access_token>
+//
+// type is "playlist", "artist", "album" or "track". Responds with up to 10 results:
+//
+//	[{ "id": "...", "name": "Queen", "image": "https://..." }]
+//
+// Playlists also have an "owner", albums and tracks their "artists". An expired token
+// gives 401, other Spotify failures 502.
+func (h *Handler) SearchSpotifyHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	searchType := r.URL.Query().Get("type")
+	userID := r.URL.Query().Get("userId")
 
-			id, _ := a["id"].(string)
-			name, _ := a["name"].(string)
+	if query == "" || (searchType != "playlist" && searchType != "artist" && searchType != "album" && searchType != "track") || userID == "" {
+		http.Error(w, "Missing or invalid query parameters", http.StatusBadRequest)
+		return
+	}
 
-			imageURL := ""
-			if images, ok := a["images"].([]any); ok && len(images) > 0 {
-				if img, ok := images[0].(map[string]any); ok {
-					imageURL, _ = img["url"].(string)
-				}
-			}
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
+		return
+	}
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	token = strings.TrimSpace(token)
 
-			results = append(results, map[string]string{
-				"id":    id,
-				"name":  name,
-				"image": imageURL,
-			})
-		}
+	results, err := h.client.Search(r.Context(), token, query, searchType, "")
+	if errors.Is(err, ErrUnauthorized) {
+		http.Error(w, "Spotify token expired", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Spotify search failed", http.StatusBadGateway)
+		return
 	}
 
-	return results, nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
 }
@@ -2,10 +2,8 @@ package spotify
 
 import (
 	"backend/internal/model"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 )
 
@@ -21,6 +19,7 @@ import (
 // Parameters:
 //   - track: model.Track object containing ID, Name, Artists (used for search query)
 //   - token: A valid Spotify access token with `user-read-private` scope
+//   - market: The host's market, so the answers are songs available there
 //
 // Returns:
 //   - []string: A slice of 3 recommended (but filtered) track names
@@ -31,55 +30,24 @@ import (
 //	["Good Vibes", "Let It Go", "Feel the Beat"]
 //
 // Filtering logic:
-//   - Reject tracks whose names contain keywords like "remix", "acoustic", "live", etc.
+//   - Reject tracks whose names contain model.VersionKeywords like "remix", "acoustic", "live", etc.
 //   - Reject tracks that match the original `track.Name` (case-insensitive)
 //   - Only return the first 3 valid alternative tracks
 //
 // Spotify endpoint used:
 //
-//	GET https://api.spotify.com/v1/search?q=<track+name+artist>&type=track&limit=10&market=PL
-func SimiliarFallback(track model.Track, token string) ([]string, error) {
+//	GET /search?q=<track+name+artist>&type=track&limit=10&market=<market>
+func (c *Client) SimiliarFallback(ctx context.Context, track model.Track, token, market string) ([]string, error) {
 	query := fmt.Sprintf("%s %s", track.Name, strings.Join(track.Artists, " "))
-	searchURL := "https://api.spotify.com/v1/search?" + url.Values{
-		"q":      {query},
-		"type":   {"track"},
-		"limit":  {"10"},
-		"market": {"PL"},
-	}.Encode()
-
-	req, _ := http.NewRequest("GET", searchURL, nil)
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var result struct {
-		Tracks struct {
-			Items []struct {
-				Name string `json:"name"`
-			} `json:"items"`
-		} `json:"tracks"`
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&result)
+	result, err := c.search(ctx, token, query, "track", 10, market)
 	if err != nil {
 		return nil, err
 	}
 
 	var tracks []string
-	bannedKeywords := []string{"acoustic", "remix", "live", "instrumental", "karaoke", "cover"}
 	seen := map[string]bool{}
 	for _, item := range result.Tracks.Items {
-		skip := false
-		for _, keyword := range bannedKeywords {
-			if strings.Contains(strings.ToLower(item.Name), keyword) {
-				skip = true
-				break
-			}
-		}
+		skip := model.IsAlternateVersion(item.Name)
 		normalizedName := strings.ToLower(item.Name)
 
 		if strings.EqualFold(track.Name, item.Name) || skip {
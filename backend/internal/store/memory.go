@@ -0,0 +1,416 @@
+package store
+
+import (
+	"backend/internal/model"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryRepository is an in-process Repository for tests and local development.
+//
+// It mirrors the Redis key layout and TTL behaviour. Values are kept as JSON so
+// callers never share maps or slices with the stored data.
+type MemoryRepository struct {
+	mu      sync.Mutex
+	values  map[string]memoryEntry
+	hashes  map[string]map[string][]byte
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+// NewMemoryRepository returns an empty in-memory Repository.
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		values:  make(map[string]memoryEntry),
+		hashes:  make(map[string]map[string][]byte),
+		expires: make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryRepository) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return m.now().Add(ttl)
+}
+
+func (m *MemoryRepository) get(key string) ([]byte, bool) {
+	entry, ok := m.values[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.expired(m.now()) {
+		delete(m.values, key)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+func (m *MemoryRepository) getJSON(key string, v any) error {
+	m.mu.Lock()
+	data, ok := m.get(key)
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (m *MemoryRepository) setJSON(key string, v any, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = memoryEntry{data: data, expiresAt: m.expiry(ttl)}
+	return nil
+}
+
+func (m *MemoryRepository) del(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values, key)
+	delete(m.hashes, key)
+	delete(m.expires, key)
+	return nil
+}
+
+func (m *MemoryRepository) hash(key string) map[string][]byte {
+	if at, ok := m.expires[key]; ok && m.now().After(at) {
+		delete(m.hashes, key)
+		delete(m.expires, key)
+	}
+	return m.hashes[key]
+}
+
+func (m *MemoryRepository) GetRoom(ctx context.Context, code string) (model.Room, error) {
+	var room model.Room
+	err := m.getJSON(roomKey(code), &room)
+	return room, err
+}
+
+func (m *MemoryRepository) CreateRoom(ctx context.Context, room model.Room) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.get(roomKey(room.Code)); ok {
+		return ErrCodeTaken
+	}
+	m.values[roomKey(room.Code)] = memoryEntry{data: data, expiresAt: m.expiry(RoomTTL)}
+	return nil
+}
+
+// UpdateRoom applies the update while holding the repository lock, so
+// concurrent updates are serialized and never conflict.
+func (m *MemoryRepository) UpdateRoom(ctx context.Context, code string, update RoomUpdate) (model.Room, error) {
+	key := roomKey(code)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.get(key)
+	if !ok {
+		return model.Room{}, ErrNotFound
+	}
+
+	var room model.Room
+	if err := json.Unmarshal(data, &room); err != nil {
+		return model.Room{}, err
+	}
+	if err := update(&room); err != nil {
+		return model.Room{}, err
+	}
+	room.Version++
+
+	updated, err := json.Marshal(room)
+	if err != nil {
+		return model.Room{}, err
+	}
+	m.values[key] = memoryEntry{data: updated, expiresAt: m.expiry(RoomTTL)}
+	return room, nil
+}
+
+func (m *MemoryRepository) DeleteRoom(ctx context.Context, code string) error {
+	return m.del(roomKey(code))
+}
+
+func (m *MemoryRepository) GetQuestions(ctx context.Context, roomCode string) ([]model.Question, error) {
+	var questions []model.Question
+	err := m.getJSON(questionsKey(roomCode), &questions)
+	return questions, err
+}
+
+func (m *MemoryRepository) SaveQuestions(ctx context.Context, roomCode string, questions []model.Question) error {
+	return m.setJSON(questionsKey(roomCode), questions, RoomTTL)
+}
+
+func (m *MemoryRepository) DeleteQuestions(ctx context.Context, roomCode string) error {
+	return m.del(questionsKey(roomCode))
+}
+
+func (m *MemoryRepository) SaveDraft(ctx context.Context, roomCode string, draft model.QuestionDraft) error {
+	return m.setJSON(draftKey(roomCode), draft, RoomTTL)
+}
+
+func (m *MemoryRepository) GetDraft(ctx context.Context, roomCode string) (model.QuestionDraft, error) {
+	var draft model.QuestionDraft
+	err := m.getJSON(draftKey(roomCode), &draft)
+	return draft, err
+}
+
+func (m *MemoryRepository) DeleteDraft(ctx context.Context, roomCode string) error {
+	return m.del(draftKey(roomCode))
+}
+
+func (m *MemoryRepository) SetQuestionTime(ctx context.Context, roomCode, questionID string, sentAt time.Time) error {
+	return m.setJSON(questionTimeKey(roomCode, questionID), sentAt.UnixMilli(), RoomTTL)
+}
+
+func (m *MemoryRepository) GetQuestionTime(ctx context.Context, roomCode, questionID string) (time.Time, error) {
+	var sentAt int64
+	if err := m.getJSON(questionTimeKey(roomCode, questionID), &sentAt); err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(sentAt), nil
+}
+
+func (m *MemoryRepository) DeleteQuestionTime(ctx context.Context, roomCode, questionID string) error {
+	return m.del(questionTimeKey(roomCode, questionID))
+}
+
+func (m *MemoryRepository) GetScore(ctx context.Context, roomCode, playerID string) (int, error) {
+	var score int
+	err := m.getJSON(scoreKey(roomCode, playerID), &score)
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	return score, err
+}
+
+func (m *MemoryRepository) AddScore(ctx context.Context, roomCode, playerID string, points int) (int, error) {
+	key := scoreKey(roomCode, playerID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	score := 0
+	if data, ok := m.get(key); ok {
+		if err := json.Unmarshal(data, &score); err != nil {
+			return 0, err
+		}
+	}
+	score += points
+	data, _ := json.Marshal(score)
+	m.values[key] = memoryEntry{data: data, expiresAt: m.expiry(RoomTTL)}
+	return score, nil
+}
+
+func (m *MemoryRepository) DeleteScore(ctx context.Context, roomCode, playerID string) error {
+	return m.del(scoreKey(roomCode, playerID))
+}
+
+func (m *MemoryRepository) GetTracks(ctx context.Context, roomCode, playerID string) ([]model.Track, error) {
+	var tracks []model.Track
+	err := m.getJSON(tracksKey(roomCode, playerID), &tracks)
+	return tracks, err
+}
+
+func (m *MemoryRepository) SaveTracks(ctx context.Context, roomCode, playerID string, tracks []model.Track) error {
+	return m.setJSON(tracksKey(roomCode, playerID), tracks, RoomTTL)
+}
+
+func (m *MemoryRepository) DeleteTracks(ctx context.Context, roomCode, playerID string) error {
+	return m.del(tracksKey(roomCode, playerID))
+}
+
+func (m *MemoryRepository) SavePlayerToken(ctx context.Context, playerID, token string) error {
+	return m.setJSON(playerTokenKey(playerID), map[string]string{"access_token": token}, RoomTTL)
+}
+
+func (m *MemoryRepository) GetPlayerToken(ctx context.Context, playerID string) (string, error) {
+	var data map[string]string
+	if err := m.getJSON(playerTokenKey(playerID), &data); err != nil {
+		return "", err
+	}
+	return data["access_token"], nil
+}
+
+func (m *MemoryRepository) SaveUserToken(ctx context.Context, spotifyID string, token model.UserToken) error {
+	return m.setJSON(userTokenKey(spotifyID), token, 0)
+}
+
+func (m *MemoryRepository) GetUserToken(ctx context.Context, spotifyID string) (model.UserToken, error) {
+	var token model.UserToken
+	err := m.getJSON(userTokenKey(spotifyID), &token)
+	return token, err
+}
+
+func (m *MemoryRepository) RecordAnswer(ctx context.Context, roomCode, questionID string, answer model.AnswerRecord) (bool, error) {
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return false, err
+	}
+
+	key := answersKey(roomCode, questionID)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	answers := m.hash(key)
+	if answers == nil {
+		answers = make(map[string][]byte)
+		m.hashes[key] = answers
+	}
+	m.expires[key] = m.expiry(RoomTTL)
+	if _, ok := answers[answer.PlayerID]; ok {
+		return false, nil
+	}
+	answers[answer.PlayerID] = data
+	return true, nil
+}
+
+func (m *MemoryRepository) GetAnswers(ctx context.Context, roomCode, questionID string) ([]model.AnswerRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	raw := m.hash(answersKey(roomCode, questionID))
+	answers := make([]model.AnswerRecord, 0, len(raw))
+	for _, data := range raw {
+		var answer model.AnswerRecord
+		if err := json.Unmarshal(data, &answer); err != nil {
+			return nil, err
+		}
+		answers = append(answers, answer)
+	}
+	return answers, nil
+}
+
+func (m *MemoryRepository) DeleteAnswers(ctx context.Context, roomCode, questionID string) error {
+	return m.del(answersKey(roomCode, questionID))
+}
+
+func (m *MemoryRepository) AppendChat(ctx context.Context, roomCode string, message model.ChatMessage, limit int) error {
+	key := chatKey(roomCode)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var messages []model.ChatMessage
+	if data, ok := m.get(key); ok {
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return err
+		}
+	}
+	messages = append(messages, message)
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return err
+	}
+	m.values[key] = memoryEntry{data: data, expiresAt: m.expiry(RoomTTL)}
+	return nil
+}
+
+func (m *MemoryRepository) GetChat(ctx context.Context, roomCode string) ([]model.ChatMessage, error) {
+	messages := []model.ChatMessage{}
+	err := m.getJSON(chatKey(roomCode), &messages)
+	if err == ErrNotFound {
+		return messages, nil
+	}
+	return messages, err
+}
+
+func (m *MemoryRepository) DeleteChat(ctx context.Context, roomCode string) error {
+	return m.del(chatKey(roomCode))
+}
+
+func (m *MemoryRepository) GetSourceTracks(ctx context.Context, kind, id, market string) (model.CachedTracks, error) {
+	var tracks model.CachedTracks
+	err := m.getJSON(sourceTracksKey(kind, id, market), &tracks)
+	return tracks, err
+}
+
+func (m *MemoryRepository) SaveSourceTracks(ctx context.Context, kind, id, market string, tracks model.CachedTracks, ttl time.Duration) error {
+	return m.setJSON(sourceTracksKey(kind, id, market), tracks, ttl)
+}
+
+func (m *MemoryRepository) SaveSummary(ctx context.Context, summary model.GameSummary, ttl time.Duration) error {
+	return m.setJSON(summaryKey(summary.RoomCode), summary, ttl)
+}
+
+func (m *MemoryRepository) GetSummary(ctx context.Context, roomCode string) (model.GameSummary, error) {
+	var summary model.GameSummary
+	err := m.getJSON(summaryKey(roomCode), &summary)
+	return summary, err
+}
+
+func (m *MemoryRepository) ExpireSummary(ctx context.Context, roomCode string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := summaryKey(roomCode)
+	if entry, ok := m.values[key]; ok && !entry.expired(m.now()) {
+		entry.expiresAt = m.expiry(ttl)
+		m.values[key] = entry
+	}
+	return nil
+}
+
+func (m *MemoryRepository) DeleteSummary(ctx context.Context, roomCode string) error {
+	return m.del(summaryKey(roomCode))
+}
+
+func (m *MemoryRepository) SavePublicRoom(ctx context.Context, listing model.PublicRoom) error {
+	data, err := json.Marshal(listing)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hashes[publicRoomsKey] == nil {
+		m.hashes[publicRoomsKey] = make(map[string][]byte)
+	}
+	m.hashes[publicRoomsKey][listing.Code] = data
+	return nil
+}
+
+func (m *MemoryRepository) DeletePublicRoom(ctx context.Context, code string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.hashes[publicRoomsKey][code]
+	delete(m.hashes[publicRoomsKey], code)
+	return ok, nil
+}
+
+func (m *MemoryRepository) ListPublicRooms(ctx context.Context) ([]model.PublicRoom, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	raw := m.hashes[publicRoomsKey]
+	rooms := make([]model.PublicRoom, 0, len(raw))
+	for _, data := range raw {
+		var listing model.PublicRoom
+		if err := json.Unmarshal(data, &listing); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, listing)
+	}
+	return rooms, nil
+}
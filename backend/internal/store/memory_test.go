@@ -0,0 +1,148 @@
+package store
+
+import (
+	"backend/internal/model"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestRepository returns a MemoryRepository with a clock the test moves.
+func newTestRepository() (*MemoryRepository, *time.Time) {
+	repo := NewMemoryRepository()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	return repo, &now
+}
+
+func TestMemoryRooms(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepository()
+
+	if _, err := repo.GetRoom(ctx, "ABC123"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetRoom of a missing room: err = %v, want ErrNotFound", err)
+	}
+	if err := repo.CreateRoom(ctx, model.Room{Code: "ABC123", HostId: "host", Players: []string{"p1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateRoom(ctx, model.Room{Code: "ABC123"}); !errors.Is(err, ErrCodeTaken) {
+		t.Fatalf("CreateRoom with a used code: err = %v, want ErrCodeTaken", err)
+	}
+
+	room, err := repo.UpdateRoom(ctx, "ABC123", func(room *model.Room) error {
+		room.Players = append(room.Players, "p2")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.Version != 1 || len(room.Players) != 2 {
+		t.Fatalf("updated room = %+v, want version 1 with two players", room)
+	}
+
+	// A failing update changes nothing.
+	errStop := errors.New("stop")
+	if _, err := repo.UpdateRoom(ctx, "ABC123", func(room *model.Room) error {
+		room.Players = nil
+		return errStop
+	}); !errors.Is(err, errStop) {
+		t.Fatalf("UpdateRoom: err = %v, want the update's error", err)
+	}
+	room, _ = repo.GetRoom(ctx, "ABC123")
+	if room.Version != 1 || len(room.Players) != 2 {
+		t.Fatalf("room after a failed update = %+v", room)
+	}
+
+	// The stored room is not shared with callers.
+	room.Players[0] = "changed"
+	if stored, _ := repo.GetRoom(ctx, "ABC123"); stored.Players[0] != "p1" {
+		t.Fatal("changing a returned room changed the stored room")
+	}
+}
+
+func TestMemoryConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepository()
+	repo.CreateRoom(ctx, model.Room{Code: "ABC123"})
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo.UpdateRoom(ctx, "ABC123", func(room *model.Room) error {
+				room.CurrentQIdx++
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	if room, _ := repo.GetRoom(ctx, "ABC123"); room.CurrentQIdx != 20 || room.Version != 20 {
+		t.Fatalf("room = %+v, want 20 updates", room)
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	repo, now := newTestRepository()
+	repo.CreateRoom(ctx, model.Room{Code: "ABC123"})
+	repo.SaveSummary(ctx, model.GameSummary{RoomCode: "ABC123"}, 0)
+
+	*now = now.Add(RoomTTL + time.Second)
+	if _, err := repo.GetRoom(ctx, "ABC123"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetRoom after RoomTTL: err = %v, want ErrNotFound", err)
+	}
+	if err := repo.CreateRoom(ctx, model.Room{Code: "ABC123"}); err != nil {
+		t.Fatalf("CreateRoom with the code of an expired room: %v", err)
+	}
+
+	// Summaries without a ttl are kept until ExpireSummary.
+	if _, err := repo.GetSummary(ctx, "ABC123"); err != nil {
+		t.Fatalf("GetSummary: %v", err)
+	}
+	repo.ExpireSummary(ctx, "ABC123", time.Hour)
+	*now = now.Add(time.Hour + time.Second)
+	if _, err := repo.GetSummary(ctx, "ABC123"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetSummary after its ttl: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryScoresAndAnswers(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepository()
+
+	if score, err := repo.GetScore(ctx, "ABC123", "p1"); err != nil || score != 0 {
+		t.Fatalf("GetScore of a new player = %d, %v; want 0, nil", score, err)
+	}
+	repo.AddScore(ctx, "ABC123", "p1", 700)
+	if score, _ := repo.AddScore(ctx, "ABC123", "p1", 500); score != 1200 {
+		t.Fatalf("AddScore = %d, want 1200", score)
+	}
+
+	answer := model.AnswerRecord{PlayerID: "p1", Selected: "A", Correct: true, Points: 900}
+	if first, _ := repo.RecordAnswer(ctx, "ABC123", "q1", answer); !first {
+		t.Fatal("first answer was not recorded")
+	}
+	answer.Selected = "B"
+	if again, _ := repo.RecordAnswer(ctx, "ABC123", "q1", answer); again {
+		t.Fatal("second answer of the same player was recorded")
+	}
+	answers, _ := repo.GetAnswers(ctx, "ABC123", "q1")
+	if len(answers) != 1 || answers[0].Selected != "A" {
+		t.Fatalf("answers = %+v, want only the first answer", answers)
+	}
+}
+
+func TestMemoryChatLimit(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepository()
+	for _, text := range []string{"a", "b", "c"} {
+		repo.AppendChat(ctx, "ABC123", model.ChatMessage{Text: text}, 2)
+	}
+	messages, _ := repo.GetChat(ctx, "ABC123")
+	if len(messages) != 2 || messages[0].Text != "b" || messages[1].Text != "c" {
+		t.Fatalf("chat = %+v, want the last 2 messages", messages)
+	}
+}
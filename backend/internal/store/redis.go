@@ -1,25 +1,382 @@
 package store
 
 import (
+	"backend/internal/model"
 	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var Ctx = context.Background()
-var Client *redis.Client
-
-func InitRedis() {
+// InitRedis connects to the Redis server given by the REDIS environment variable.
+// It panics if the server cannot be reached.
+func InitRedis() *redis.Client {
 	redisAddr := os.Getenv("REDIS")
-	Client = redis.NewClient(&redis.Options{
+	client := redis.NewClient(&redis.Options{
 		Addr:     redisAddr,
 		Password: "",
 		DB:       0,
 		Protocol: 2,
 	})
 
-	if err := Client.Ping(Ctx).Err(); err != nil {
+	if err := client.Ping(context.Background()).Err(); err != nil {
 		panic(err)
 	}
+	return client
+}
+
+// RedisRepository is a Repository backed by Redis. Values are stored as JSON
+// under the same keys the game has always used, e.g. "room:{code}".
+type RedisRepository struct {
+	client *redis.Client
+}
+
+var _ Repository = (*RedisRepository)(nil)
+
+// NewRedisRepository returns a Repository using the given Redis client.
+func NewRedisRepository(client *redis.Client) *RedisRepository {
+	return &RedisRepository{client: client}
+}
+
+func (r *RedisRepository) getJSON(ctx context.Context, key string, v any) error {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (r *RedisRepository) setJSON(ctx context.Context, key string, v any, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, key, data, ttl).Err()
+}
+
+func (r *RedisRepository) GetRoom(ctx context.Context, code string) (model.Room, error) {
+	var room model.Room
+	err := r.getJSON(ctx, roomKey(code), &room)
+	return room, err
+}
+
+func (r *RedisRepository) CreateRoom(ctx context.Context, room model.Room) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+	created, err := r.client.SetNX(ctx, roomKey(room.Code), data, RoomTTL).Result()
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrCodeTaken
+	}
+	return nil
+}
+
+// UpdateRoom runs a WATCH/MULTI transaction on "room:{code}". If another
+// client writes the room between the read and the write, the transaction
+// fails and the update is retried on the fresh value.
+func (r *RedisRepository) UpdateRoom(ctx context.Context, code string, update RoomUpdate) (model.Room, error) {
+	key := roomKey(code)
+	var room model.Room
+
+	txf := func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		room = model.Room{}
+		if err := json.Unmarshal(data, &room); err != nil {
+			return err
+		}
+		if err := update(&room); err != nil {
+			return err
+		}
+		room.Version++
+
+		updated, err := json.Marshal(room)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, updated, RoomTTL)
+			return nil
+		})
+		return err
+	}
+
+	for attempt := range MaxUpdateRetries {
+		err := r.client.Watch(ctx, txf, key)
+		if errors.Is(err, redis.TxFailedErr) {
+			// Back off with jitter so competing writers do not collide again.
+			backoff := time.Duration(rand.IntN((attempt+1)*10)+1) * time.Millisecond
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return model.Room{}, ctx.Err()
+			}
+			continue
+		}
+		if err != nil {
+			return model.Room{}, err
+		}
+		return room, nil
+	}
+	return model.Room{}, ErrConflict
+}
+
+func (r *RedisRepository) DeleteRoom(ctx context.Context, code string) error {
+	return r.client.Del(ctx, roomKey(code)).Err()
+}
+
+func (r *RedisRepository) GetQuestions(ctx context.Context, roomCode string) ([]model.Question, error) {
+	var questions []model.Question
+	err := r.getJSON(ctx, questionsKey(roomCode), &questions)
+	return questions, err
+}
+
+func (r *RedisRepository) SaveQuestions(ctx context.Context, roomCode string, questions []model.Question) error {
+	return r.setJSON(ctx, questionsKey(roomCode), questions, RoomTTL)
+}
+
+func (r *RedisRepository) DeleteQuestions(ctx context.Context, roomCode string) error {
+	return r.client.Del(ctx, questionsKey(roomCode)).Err()
+}
+
+func (r *RedisRepository) SaveDraft(ctx context.Context, roomCode string, draft model.QuestionDraft) error {
+	return r.setJSON(ctx, draftKey(roomCode), draft, RoomTTL)
+}
+
+func (r *RedisRepository) GetDraft(ctx context.Context, roomCode string) (model.QuestionDraft, error) {
+	var draft model.QuestionDraft
+	err := r.getJSON(ctx, draftKey(roomCode), &draft)
+	return draft, err
+}
+
+func (r *RedisRepository) DeleteDraft(ctx context.Context, roomCode string) error {
+	return r.client.Del(ctx, draftKey(roomCode)).Err()
+}
+
+func (r *RedisRepository) SetQuestionTime(ctx context.Context, roomCode, questionID string, sentAt time.Time) error {
+	return r.client.Set(ctx, questionTimeKey(roomCode, questionID), sentAt.UnixMilli(), RoomTTL).Err()
+}
+
+func (r *RedisRepository) GetQuestionTime(ctx context.Context, roomCode, questionID string) (time.Time, error) {
+	sentAt, err := r.client.Get(ctx, questionTimeKey(roomCode, questionID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, ErrNotFound
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(sentAt), nil
+}
+
+func (r *RedisRepository) DeleteQuestionTime(ctx context.Context, roomCode, questionID string) error {
+	return r.client.Del(ctx, questionTimeKey(roomCode, questionID)).Err()
+}
+
+func (r *RedisRepository) GetScore(ctx context.Context, roomCode, playerID string) (int, error) {
+	data, err := r.client.Get(ctx, scoreKey(roomCode, playerID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(data)
+}
+
+func (r *RedisRepository) AddScore(ctx context.Context, roomCode, playerID string, points int) (int, error) {
+	key := scoreKey(roomCode, playerID)
+	pipe := r.client.TxPipeline()
+	incr := pipe.IncrBy(ctx, key, int64(points))
+	pipe.Expire(ctx, key, RoomTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+func (r *RedisRepository) DeleteScore(ctx context.Context, roomCode, playerID string) error {
+	return r.client.Del(ctx, scoreKey(roomCode, playerID)).Err()
+}
+
+func (r *RedisRepository) GetTracks(ctx context.Context, roomCode, playerID string) ([]model.Track, error) {
+	var tracks []model.Track
+	err := r.getJSON(ctx, tracksKey(roomCode, playerID), &tracks)
+	return tracks, err
+}
+
+func (r *RedisRepository) SaveTracks(ctx context.Context, roomCode, playerID string, tracks []model.Track) error {
+	return r.setJSON(ctx, tracksKey(roomCode, playerID), tracks, RoomTTL)
+}
+
+func (r *RedisRepository) DeleteTracks(ctx context.Context, roomCode, playerID string) error {
+	return r.client.Del(ctx, tracksKey(roomCode, playerID)).Err()
+}
+
+func (r *RedisRepository) SavePlayerToken(ctx context.Context, playerID, token string) error {
+	return r.setJSON(ctx, playerTokenKey(playerID), map[string]string{"access_token": token}, RoomTTL)
+}
+
+func (r *RedisRepository) GetPlayerToken(ctx context.Context, playerID string) (string, error) {
+	var data map[string]string
+	if err := r.getJSON(ctx, playerTokenKey(playerID), &data); err != nil {
+		return "", err
+	}
+	return data["access_token"], nil
+}
+
+func (r *RedisRepository) SaveUserToken(ctx context.Context, spotifyID string, token model.UserToken) error {
+	return r.setJSON(ctx, userTokenKey(spotifyID), token, 0)
+}
+
+func (r *RedisRepository) GetUserToken(ctx context.Context, spotifyID string) (model.UserToken, error) {
+	var token model.UserToken
+	err := r.getJSON(ctx, userTokenKey(spotifyID), &token)
+	return token, err
+}
+
+func (r *RedisRepository) RecordAnswer(ctx context.Context, roomCode, questionID string, answer model.AnswerRecord) (bool, error) {
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return false, err
+	}
+	key := answersKey(roomCode, questionID)
+	recorded, err := r.client.HSetNX(ctx, key, answer.PlayerID, data).Result()
+	if err != nil {
+		return false, err
+	}
+	r.client.Expire(ctx, key, RoomTTL)
+	return recorded, nil
+}
+
+func (r *RedisRepository) GetAnswers(ctx context.Context, roomCode, questionID string) ([]model.AnswerRecord, error) {
+	raw, err := r.client.HGetAll(ctx, answersKey(roomCode, questionID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	answers := make([]model.AnswerRecord, 0, len(raw))
+	for _, value := range raw {
+		var answer model.AnswerRecord
+		if err := json.Unmarshal([]byte(value), &answer); err != nil {
+			return nil, err
+		}
+		answers = append(answers, answer)
+	}
+	return answers, nil
+}
+
+func (r *RedisRepository) DeleteAnswers(ctx context.Context, roomCode, questionID string) error {
+	return r.client.Del(ctx, answersKey(roomCode, questionID)).Err()
+}
+
+func (r *RedisRepository) AppendChat(ctx context.Context, roomCode string, message model.ChatMessage, limit int) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	key := chatKey(roomCode)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		pipe.LTrim(ctx, key, int64(-limit), -1)
+		pipe.Expire(ctx, key, RoomTTL)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) GetChat(ctx context.Context, roomCode string) ([]model.ChatMessage, error) {
+	raw, err := r.client.LRange(ctx, chatKey(roomCode), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]model.ChatMessage, 0, len(raw))
+	for _, value := range raw {
+		var message model.ChatMessage
+		if err := json.Unmarshal([]byte(value), &message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (r *RedisRepository) DeleteChat(ctx context.Context, roomCode string) error {
+	return r.client.Del(ctx, chatKey(roomCode)).Err()
+}
+
+func (r *RedisRepository) GetSourceTracks(ctx context.Context, kind, id, market string) (model.CachedTracks, error) {
+	var tracks model.CachedTracks
+	err := r.getJSON(ctx, sourceTracksKey(kind, id, market), &tracks)
+	return tracks, err
+}
+
+func (r *RedisRepository) SaveSourceTracks(ctx context.Context, kind, id, market string, tracks model.CachedTracks, ttl time.Duration) error {
+	return r.setJSON(ctx, sourceTracksKey(kind, id, market), tracks, ttl)
+}
+
+func (r *RedisRepository) SaveSummary(ctx context.Context, summary model.GameSummary, ttl time.Duration) error {
+	return r.setJSON(ctx, summaryKey(summary.RoomCode), summary, ttl)
+}
+
+func (r *RedisRepository) GetSummary(ctx context.Context, roomCode string) (model.GameSummary, error) {
+	var summary model.GameSummary
+	err := r.getJSON(ctx, summaryKey(roomCode), &summary)
+	return summary, err
+}
+
+func (r *RedisRepository) ExpireSummary(ctx context.Context, roomCode string, ttl time.Duration) error {
+	return r.client.Expire(ctx, summaryKey(roomCode), ttl).Err()
+}
+
+func (r *RedisRepository) DeleteSummary(ctx context.Context, roomCode string) error {
+	return r.client.Del(ctx, summaryKey(roomCode)).Err()
+}
+
+func (r *RedisRepository) SavePublicRoom(ctx context.Context, listing model.PublicRoom) error {
+	data, err := json.Marshal(listing)
+	if err != nil {
+		return err
+	}
+	return r.client.HSet(ctx, publicRoomsKey, listing.Code, data).Err()
+}
+
+func (r *RedisRepository) DeletePublicRoom(ctx context.Context, code string) (bool, error) {
+	removed, err := r.client.HDel(ctx, publicRoomsKey, code).Result()
+	return removed > 0, err
+}
+
+func (r *RedisRepository) ListPublicRooms(ctx context.Context) ([]model.PublicRoom, error) {
+	raw, err := r.client.HGetAll(ctx, publicRoomsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rooms := make([]model.PublicRoom, 0, len(raw))
+	for _, value := range raw {
+		var listing model.PublicRoom
+		if err := json.Unmarshal([]byte(value), &listing); err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, listing)
+	}
+	return rooms, nil
 }
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"backend/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+// RoomTTL is how long room-related data is kept after the last write.
+const RoomTTL = 60 * time.Minute
+
+// ErrNotFound is returned when the requested entry does not exist or has expired.
+var ErrNotFound = errors.New("store: not found")
+
+// ErrCodeTaken is returned by CreateRoom when a live room already uses the code.
+var ErrCodeTaken = errors.New("store: room code already in use")
+
+// ErrConflict is returned by UpdateRoom when the room kept changing concurrently
+// and the update could not be applied within MaxUpdateRetries attempts.
+var ErrConflict = errors.New("store: concurrent update conflict")
+
+// MaxUpdateRetries is how many times UpdateRoom re-reads and re-applies an
+// update after losing a race with another writer.
+const MaxUpdateRetries = 10
+
+// RoomUpdate mutates a room inside UpdateRoom. It may be called several times
+// if the update has to be retried, so it must not have side effects beyond the
+// room itself. Returning an error aborts the update and the error is passed
+// back to the caller unchanged.
+type RoomUpdate func(room *model.Room) error
+
+// Repository is the typed storage used by the HTTP handlers and the quiz loop.
+//
+// Room-scoped data (rooms, questions, scores, tracks, answers) expires after RoomTTL.
+// Getters return ErrNotFound when an entry is missing, except GetScore which
+// treats a missing score as 0.
+type Repository interface {
+	GetRoom(ctx context.Context, code string) (model.Room, error)
+	// CreateRoom stores a new room only if no room uses its code yet (SETNX),
+	// otherwise it returns ErrCodeTaken. Later changes must go through UpdateRoom.
+	CreateRoom(ctx context.Context, room model.Room) error
+	// UpdateRoom atomically applies update to the stored room and returns the
+	// result. The room's Version is incremented on every successful update.
+	UpdateRoom(ctx context.Context, code string, update RoomUpdate) (model.Room, error)
+	DeleteRoom(ctx context.Context, code string) error
+
+	GetQuestions(ctx context.Context, roomCode string) ([]model.Question, error)
+	SaveQuestions(ctx context.Context, roomCode string, questions []model.Question) error
+	DeleteQuestions(ctx context.Context, roomCode string) error
+	// SaveDraft stores the questions generated in the background for the next game.
+	SaveDraft(ctx context.Context, roomCode string, draft model.QuestionDraft) error
+	GetDraft(ctx context.Context, roomCode string) (model.QuestionDraft, error)
+	DeleteDraft(ctx context.Context, roomCode string) error
+	SetQuestionTime(ctx context.Context, roomCode, questionID string, sentAt time.Time) error
+	GetQuestionTime(ctx context.Context, roomCode, questionID string) (time.Time, error)
+	DeleteQuestionTime(ctx context.Context, roomCode, questionID string) error
+
+	GetScore(ctx context.Context, roomCode, playerID string) (int, error)
+	AddScore(ctx context.Context, roomCode, playerID string, points int) (int, error)
+	DeleteScore(ctx context.Context, roomCode, playerID string) error
+
+	GetTracks(ctx context.Context, roomCode, playerID string) ([]model.Track, error)
+	SaveTracks(ctx context.Context, roomCode, playerID string, tracks []model.Track) error
+	DeleteTracks(ctx context.Context, roomCode, playerID string) error
+
+	// SavePlayerToken stores the access token a player used to create or join a room.
+	SavePlayerToken(ctx context.Context, playerID, token string) error
+	GetPlayerToken(ctx context.Context, playerID string) (string, error)
+	// SaveUserToken stores the OAuth tokens of a Spotify user without expiry.
+	SaveUserToken(ctx context.Context, spotifyID string, token model.UserToken) error
+	GetUserToken(ctx context.Context, spotifyID string) (model.UserToken, error)
+
+	// RecordAnswer stores a player's answer to a question. It returns false
+	// without overwriting anything if the player has already answered.
+	RecordAnswer(ctx context.Context, roomCode, questionID string, answer model.AnswerRecord) (bool, error)
+	GetAnswers(ctx context.Context, roomCode, questionID string) ([]model.AnswerRecord, error)
+	DeleteAnswers(ctx context.Context, roomCode, questionID string) error
+
+	// AppendChat adds a message to the room's chat history, keeping only the
+	// last limit messages.
+	AppendChat(ctx context.Context, roomCode string, message model.ChatMessage, limit int) error
+	// GetChat returns the room's chat history, oldest message first.
+	GetChat(ctx context.Context, roomCode string) ([]model.ChatMessage, error)
+	DeleteChat(ctx context.Context, roomCode string) error
+
+	// GetSourceTracks returns the cached tracks of a playlist or an artist.
+	// kind is "playlist" or "artist"; tracks are cached per market.
+	GetSourceTracks(ctx context.Context, kind, id, market string) (model.CachedTracks, error)
+	SaveSourceTracks(ctx context.Context, kind, id, market string, tracks model.CachedTracks, ttl time.Duration) error
+
+	// SaveSummary stores the room's game summary; a zero ttl keeps it until
+	// ExpireSummary is called.
+	SaveSummary(ctx context.Context, summary model.GameSummary, ttl time.Duration) error
+	GetSummary(ctx context.Context, roomCode string) (model.GameSummary, error)
+	// ExpireSummary makes the room's summary expire after ttl. It does nothing
+	// if the room has no summary.
+	ExpireSummary(ctx context.Context, roomCode string, ttl time.Duration) error
+	DeleteSummary(ctx context.Context, roomCode string) error
+
+	// SavePublicRoom adds or replaces the room's entry in the lobby browser index.
+	SavePublicRoom(ctx context.Context, listing model.PublicRoom) error
+	// DeletePublicRoom removes the room from the index and reports whether it was listed.
+	DeletePublicRoom(ctx context.Context, code string) (bool, error)
+	// ListPublicRooms returns all indexed rooms. Entries not updated within
+	// RoomTTL belong to expired rooms; lobby.Repository unlists them.
+	ListPublicRooms(ctx context.Context) ([]model.PublicRoom, error)
+}
+
+func roomKey(code string) string {
+	return "room:" + code
+}
+
+func questionsKey(roomCode string) string {
+	return "questions:" + roomCode
+}
+
+func draftKey(roomCode string) string {
+	return "draft:" + roomCode
+}
+
+func questionTimeKey(roomCode, questionID string) string {
+	return "question-time:" + roomCode + ":" + questionID
+}
+
+func scoreKey(roomCode, playerID string) string {
+	return "score:" + roomCode + ":" + playerID
+}
+
+func tracksKey(roomCode, playerID string) string {
+	return "tracks:" + roomCode + ":" + playerID
+}
+
+func playerTokenKey(playerID string) string {
+	return "player:" + playerID
+}
+
+func userTokenKey(spotifyID string) string {
+	return "user:" + spotifyID
+}
+
+func answersKey(roomCode, questionID string) string {
+	return "answers:" + roomCode + ":" + questionID
+}
+
+func chatKey(roomCode string) string {
+	return "chat:" + roomCode
+}
+
+func sourceTracksKey(kind, id, market string) string {
+	return "source-tracks:" + kind + ":" + id + ":" + market
+}
+
+func summaryKey(roomCode string) string {
+	return "summary:" + roomCode
+}
+
+// publicRoomsKey is the hash of lobby browser entries, keyed by room code.
+const publicRoomsKey = "public-rooms"
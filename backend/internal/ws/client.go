@@ -14,16 +14,49 @@ const (
 	writeWait  = 10 * time.Second
 )
 
+// Incoming messages are rate-limited per connection with a token bucket: a
+// client may send messageBurst messages at once and one more every
+// messageInterval. Messages over the limit are dropped and answered with
+// {"type": "rate-limited", "data": {"messageType": "..."}}.
+const (
+	messageBurst    = 5
+	messageInterval = time.Second
+)
+
+// rateLimiter is the token bucket of one connection. It is only used by the
+// connection's readPump.
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{tokens: messageBurst, last: time.Now()}
+}
+
+// allow takes a token if one is available.
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.tokens = min(messageBurst, l.tokens+float64(now.Sub(l.last))/float64(messageInterval))
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
 // Client represents a single WebSocket connection.
 // Each connected player gets their own Client instance.
 //
 // The client is associated with a specific room (by roomCode)
 // and communicates with the Hub via send and receive channels.
 type Client struct {
+	hub      *Hub
 	conn     *websocket.Conn
 	send     chan []byte
 	roomCode string
 	playerID string
+	limiter  *rateLimiter
 }
 
 type SocketMessage struct {
@@ -44,6 +77,7 @@ func (c *Client) readPump() {
 		if r := recover(); r != nil {
 			log.Printf("Recovered from panic in readPump: %v", r)
 		}
+		c.hub.unregister <- c
 		c.conn.Close()
 	}()
 
@@ -71,10 +105,18 @@ func (c *Client) readPump() {
 			continue
 		}
 
-		switch socketMsg.Type {
-		default:
+		handler, ok := c.hub.handlers[socketMsg.Type]
+		if !ok {
 			log.Println("unknown message type:", socketMsg.Type)
+			continue
+		}
+		if !c.limiter.allow(time.Now()) {
+			c.hub.Send(c.roomCode, c.playerID, "rate-limited", map[string]string{
+				"messageType": socketMsg.Type,
+			})
+			continue
 		}
+		handler(c.roomCode, c.playerID, socketMsg.Data)
 	}
 }
 
@@ -14,7 +14,10 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func WSHandler(w http.ResponseWriter, r *http.Request) {
+// WSHandler upgrades requests to /ws/{roomCode}/{playerId} to a WebSocket
+// connection and registers the client with the hub. Players rejected by the
+// hub's AdmitFunc are not upgraded.
+func (h *Hub) WSHandler(w http.ResponseWriter, r *http.Request) {
 	parts := strings.Split(r.URL.Path, "/")
 	if len(parts) < 4 {
 		http.Error(w, "invalid websocket path", http.StatusBadRequest)
@@ -24,6 +27,9 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 	roomCode := parts[2]
 	playerID := parts[3]
 	log.Printf("Incoming WS: /ws/%s/%s\n", roomCode, playerID)
+	if h.admit != nil && !h.admit(w, r, roomCode, playerID) {
+		return
+	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -31,12 +37,14 @@ func WSHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	client := &Client{
+		hub:      h,
 		conn:     conn,
 		send:     make(chan []byte, 256),
 		roomCode: roomCode,
 		playerID: playerID,
+		limiter:  newRateLimiter(),
 	}
-	GlobalHub.register <- client
+	h.register <- client
 
 	go client.writePump()
 	go client.readPump()
@@ -1,12 +1,56 @@
 package ws
 
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+)
+
 // Hub manages all active WebSocket clients, grouped by roomCode.
 // It handles client registration, unregistration and broadcasting messages to all clients in a room.
 type Hub struct {
 	rooms      map[string]map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
+	disconnect chan playerRef
+	direct     chan directMessage
+	online     chan onlineQuery
 	Broadcast  chan BroadcastMessage
+
+	onPresence []PresenceFunc
+	handlers   map[string]MessageHandler
+	admit      AdmitFunc
+}
+
+// MessageHandler handles a message of one type sent by a client. data is the
+// raw "data" field of the message.
+type MessageHandler func(roomCode, playerID string, data json.RawMessage)
+
+// PresenceFunc is called when a player's first connection to a room opens
+// (online is true) or their last connection to it closes (online is false).
+type PresenceFunc func(roomCode, playerID string, online bool)
+
+// AdmitFunc decides whether a player may connect to a room. It is called
+// before the connection is upgraded; to reject it, it writes the error
+// response and returns false.
+type AdmitFunc func(w http.ResponseWriter, r *http.Request, roomCode, playerID string) bool
+
+type onlineQuery struct {
+	roomCode string
+	reply    chan []string
+}
+
+// playerRef identifies the connections of one player in a room.
+type playerRef struct {
+	roomCode string
+	playerID string
+}
+
+// directMessage is a message for the connections of one player.
+type directMessage struct {
+	playerRef
+	data []byte
 }
 
 type BroadcastMessage struct {
@@ -19,11 +63,119 @@ func NewHub() *Hub {
 		rooms:      make(map[string]map[*Client]bool),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		disconnect: make(chan playerRef),
+		direct:     make(chan directMessage),
+		online:     make(chan onlineQuery),
 		Broadcast:  make(chan BroadcastMessage),
+		handlers:   make(map[string]MessageHandler),
 	}
 }
 
-var GlobalHub = NewHub()
+// encode returns the {"type": msgType, "data": data} message. The "data"
+// field is omitted when data is nil.
+func encode(msgType string, data any) ([]byte, bool) {
+	message := map[string]any{
+		"type": msgType,
+	}
+	if data != nil {
+		message["data"] = data
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("failed to marshal %s message: %v", msgType, err)
+		return nil, false
+	}
+	return payload, true
+}
+
+// Emit broadcasts a {"type": msgType, "data": data} message to every client in the room.
+// The "data" field is omitted when data is nil.
+func (h *Hub) Emit(roomCode string, msgType string, data any) {
+	payload, ok := encode(msgType, data)
+	if !ok {
+		return
+	}
+	h.Broadcast <- BroadcastMessage{
+		RoomCode: roomCode,
+		Data:     payload,
+	}
+}
+
+// Send sends a {"type": msgType, "data": data} message to every connection of
+// one player in the room, e.g. to reply to a message only its sender should see.
+func (h *Hub) Send(roomCode, playerID string, msgType string, data any) {
+	payload, ok := encode(msgType, data)
+	if !ok {
+		return
+	}
+	h.direct <- directMessage{
+		playerRef: playerRef{roomCode: roomCode, playerID: playerID},
+		data:      payload,
+	}
+}
+
+// OnPresence adds a function called when players come online or go offline
+// in a room. It must be called before Run. The functions run on their own
+// goroutines, so they may use the hub.
+func (h *Hub) OnPresence(fn PresenceFunc) {
+	h.onPresence = append(h.onPresence, fn)
+}
+
+// Handle registers the handler for client messages of the given type, e.g.
+// {"type": "ready", "data": {...}}. It must be called before the hub serves
+// connections.
+func (h *Hub) Handle(msgType string, handler MessageHandler) {
+	h.handlers[msgType] = handler
+}
+
+// Admit sets the function that checks players before their connection is
+// upgraded. It must be called before the hub serves connections.
+func (h *Hub) Admit(fn AdmitFunc) {
+	h.admit = fn
+}
+
+// Online returns the IDs of the players connected to the room.
+func (h *Hub) Online(roomCode string) []string {
+	reply := make(chan []string, 1)
+	h.online <- onlineQuery{roomCode: roomCode, reply: reply}
+	return <-reply
+}
+
+// connected reports whether the player still has a connection to the room.
+func connected(clients map[*Client]bool, playerID string) bool {
+	for client := range clients {
+		if client.playerID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Hub) notify(roomCode, playerID string, online bool) {
+	for _, fn := range h.onPresence {
+		go fn(roomCode, playerID, online)
+	}
+}
+
+// drop removes the client from its room, closes its send channel and reports
+// the player offline if it was their last connection.
+func (h *Hub) drop(clients map[*Client]bool, client *Client) {
+	delete(clients, client)
+	close(client.send)
+	if len(clients) == 0 {
+		delete(h.rooms, client.roomCode)
+	}
+	if !connected(clients, client.playerID) {
+		h.notify(client.roomCode, client.playerID, false)
+	}
+}
+
+// Disconnect closes every connection of the player in the room. Messages
+// already queued for the player are still delivered before the socket closes.
+func (h *Hub) Disconnect(roomCode, playerID string) {
+	h.disconnect <- playerRef{roomCode: roomCode, playerID: playerID}
+}
 
 func (h *Hub) Run() {
 	for {
@@ -34,27 +186,57 @@ func (h *Hub) Run() {
 				clients = make(map[*Client]bool)
 				h.rooms[client.roomCode] = clients
 			}
+			if !connected(clients, client.playerID) {
+				h.notify(client.roomCode, client.playerID, true)
+			}
 			clients[client] = true
 
 		case client := <-h.unregister:
 			if clients, ok := h.rooms[client.roomCode]; ok {
 				if _, ok := clients[client]; ok {
-					delete(clients, client)
-					close(client.send)
-					if len(clients) == 0 {
-						delete(h.rooms, client.roomCode)
+					h.drop(clients, client)
+				}
+			}
+
+		case ref := <-h.disconnect:
+			if clients, ok := h.rooms[ref.roomCode]; ok {
+				for client := range clients {
+					if client.playerID == ref.playerID {
+						h.drop(clients, client)
 					}
 				}
 			}
 
+		case msg := <-h.direct:
+			if clients, ok := h.rooms[msg.roomCode]; ok {
+				for client := range clients {
+					if client.playerID != msg.playerID {
+						continue
+					}
+					select {
+					case client.send <- msg.data:
+					default:
+						h.drop(clients, client)
+					}
+				}
+			}
+
+		case query := <-h.online:
+			var players []string
+			for client := range h.rooms[query.roomCode] {
+				if !slices.Contains(players, client.playerID) {
+					players = append(players, client.playerID)
+				}
+			}
+			query.reply <- players
+
 		case msg := <-h.Broadcast:
 			if clients, ok := h.rooms[msg.RoomCode]; ok {
 				for client := range clients {
 					select {
 					case client.send <- msg.Data:
 					default:
-						close(client.send)
-						delete(clients, client)
+						h.drop(clients, client)
 					}
 				}
 			}
@@ -2,6 +2,7 @@ import { useNavigate } from "react-router-dom";
 import { useEffect, useState } from "react";
 import LoginPage from "./LoginPage";
 import axios from "axios";
+import { apiErrorMessage } from "../lib/apiError";
 const HomePage = () => {
     const player_ID: string | null = localStorage.getItem("spotify_id");
     const [playerName, setPlayerName] = useState<string>("");
@@ -75,14 +76,8 @@ const HomePage = () => {
             localStorage.setItem("isHost", "false");
             navigate(`/room/${res.data.roomCode}/lobby`, { state: playerName });
         } catch (err) {
-            if (axios.isAxiosError(err) && err.response?.status === 400) {
-                alert(err.response?.data);
-            } else if (axios.isAxiosError(err) && err.response?.status === 404) {
-                alert(err.response?.data);
-            } else if (axios.isAxiosError(err) && err.response?.status === 500) {
-                alert(err.response?.data);
-            } else if (axios.isAxiosError(err) && err.response?.status === 409) {
-                alert(err.response?.data);
+            if (axios.isAxiosError(err)) {
+                alert(apiErrorMessage(err));
             }
             console.error(err);
             localStorage.removeItem("roomCode");
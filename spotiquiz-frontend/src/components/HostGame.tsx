@@ -2,11 +2,11 @@ import { useEffect } from "react";
 import axios from "axios";
 import useSpotifyPlayer from "../hooks/useSpotifyPlayer";
 import TimedProgress from "./TimedProgress";
-import type { Question } from "../pages/GamePage";
+import type { Question, ScoreEntry } from "../pages/GamePage";
 
 type Props = {
     question: Question | null;
-    scoreboard: Record<string, number> | null;
+    scoreboard: ScoreEntry[] | null;
     view: string;
     accessToken: string | null;
     playerID: string;
@@ -88,21 +88,19 @@ const HostGame: React.FC<Props> = ({
                             Player Rankings
                         </div>
                         <ul className="divide-y divide-gray-200 rounded-b-sm">
-                            {Object.entries(scoreboard)
-                                .sort(([, a], [, b]) => b - a)
-                                .map(([playerId, score], idx) => (
-                                    <li
-                                        key={playerId}
-                                        className="flex justify-between px-4 py-3 bg-indigo-50 transition rounded-b-sm"
-                                    >
-                                        <span className="font-medium">
-                                            #{idx + 1} {playerId}
-                                        </span>
-                                        <span className="text-indigo-700 font-semibold">
-                                            {score} pts
-                                        </span>
-                                    </li>
-                                ))}
+                            {scoreboard.map((entry, idx) => (
+                                <li
+                                    key={entry.playerId}
+                                    className="flex justify-between px-4 py-3 bg-indigo-50 transition rounded-b-sm"
+                                >
+                                    <span className="font-medium">
+                                        #{idx + 1} {entry.displayName}
+                                    </span>
+                                    <span className="text-indigo-700 font-semibold">
+                                        {entry.score} pts
+                                    </span>
+                                </li>
+                            ))}
                         </ul>
                     </div>
                 </>
@@ -2,6 +2,8 @@ const clientId = import.meta.env.VITE_SPOTIFY_CLIENT_ID;
 const redirectUri = import.meta.env.VITE_SPOTIFY_REDIRECT_URI;
 const scopes = [
     "user-read-recently-played",
+    "user-top-read",
+    "user-library-read",
     "user-read-private",
     "user-read-email",
     "streaming",
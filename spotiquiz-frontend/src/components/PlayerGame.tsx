@@ -1,11 +1,11 @@
 import { useEffect, useState } from "react";
 import axios from "axios";
-import type { Question } from "../pages/GamePage";
+import type { Question, ScoreEntry } from "../pages/GamePage";
 import TimedProgress from "./TimedProgress";
 
 type Props = {
     question: Question | null;
-    scoreboard: Record<string, number> | null;
+    scoreboard: ScoreEntry[] | null;
     view: string;
     hasAnswered: boolean;
     setHasAnswered: React.Dispatch<React.SetStateAction<boolean>>;
@@ -30,9 +30,7 @@ const PlayerGame: React.FC<Props> = ({
     const name = localStorage.getItem("name");
     useEffect(() => {
         const pos = scoreboard
-            ? Object.entries(scoreboard)
-                .sort(([, a], [, b]) => b - a)
-                .findIndex(([id]) => id === name) + 1
+            ? scoreboard.findIndex((entry) => entry.playerId === name) + 1
             : 1;
 
         setPosition(pos);
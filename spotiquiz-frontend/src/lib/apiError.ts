@@ -0,0 +1,34 @@
+import axios from "axios";
+
+// ApiError is the JSON body of a backend error: a stable code and a
+// human-readable message. Some endpoints still answer with plain text.
+export type ApiError = {
+    error: string;
+    message: string;
+};
+
+// apiError returns the error body of a failed request, if it has one.
+export function apiError(err: unknown): ApiError | null {
+    if (!axios.isAxiosError(err)) return null;
+    const data = err.response?.data;
+    if (data && typeof data === "object" && "error" in data) {
+        const body = data as Partial<ApiError>;
+        return {
+            error: String(body.error),
+            message: String(body.message ?? body.error),
+        };
+    }
+    return null;
+}
+
+// apiErrorMessage returns a message to show for a failed request.
+export function apiErrorMessage(err: unknown): string {
+    const body = apiError(err);
+    if (body) return body.message;
+    if (axios.isAxiosError(err)) {
+        const data = err.response?.data;
+        if (typeof data === "string" && data.trim()) return data.trim();
+        return err.message;
+    }
+    return "Something went wrong";
+}
@@ -10,6 +10,12 @@ export type Question = {
     correct: string;
     positionMs: number;
 };
+export type ScoreEntry = {
+    playerId: string;
+    displayName: string;
+    avatar?: string;
+    score: number;
+};
 const GamePage = () => {
     const navigate = useNavigate();
     const isHost = localStorage.getItem("isHost") === "true";
@@ -20,9 +26,7 @@ const GamePage = () => {
     const location = useLocation();
     const playerName = location.state;
     const [question, setQuestion] = useState<Question | null>(null);
-    const [scoreboard, setScoreboard] = useState<Record<string, number> | null>(
-        null,
-    );
+    const [scoreboard, setScoreboard] = useState<ScoreEntry[] | null>(null);
     const [view, setView] = useState<string>("");
     const socketRef = useRef<WebSocket | null>(null);
     const [hasAnswered, setHasAnswered] = useState<boolean>(false);
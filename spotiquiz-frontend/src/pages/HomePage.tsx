@@ -4,10 +4,28 @@ import LoginPage from "../components/LoginPage";
 import axios from "axios";
 import CustomDialog from "../components/CustomDialog";
 import CustomAlert from "../components/CustomAlert";
+import { apiError, apiErrorMessage } from "../lib/apiError";
+
+// joinErrorTitles are the alert titles of join errors, by error code.
+const joinErrorTitles: Record<string, string> = {
+    room_not_found: "Room not found",
+    room_full: "Room is full",
+    room_locked: "Room is locked",
+    game_in_progress: "Game already started",
+    player_banned: "Banned",
+    player_exists: "Name taken",
+    display_name_taken: "Name taken",
+    invalid_display_name: "Invalid name",
+    display_name_not_allowed: "Invalid name",
+    password_required: "Password required",
+    wrong_password: "Wrong password",
+};
 const HomePage = () => {
     const player_ID: string | null = localStorage.getItem("spotify_id");
     const apiUrl: string = import.meta.env.VITE_BACKEND_API_URL;
-    const [roomCode, setRoomCode] = useState<string>("");
+    const [roomCode, setRoomCode] = useState<string>(
+        () => new URLSearchParams(window.location.search).get("join") ?? "",
+    );
     const navigate = useNavigate();
     const [error, setError] = useState<string | null>(null);
     const [errorTitle, setErrorTitle] = useState<
@@ -30,7 +48,7 @@ const HomePage = () => {
                 localStorage.removeItem("access_token");
                 console.log(err);
                 if (axios.isAxiosError(err)) {
-                    setError(err.response?.data);
+                    setError(apiErrorMessage(err));
                 }
             }
         };
@@ -65,7 +83,7 @@ const HomePage = () => {
             localStorage.removeItem("isHost");
             if (axios.isAxiosError(err)) {
                 setErrorTitle(err.response?.status);
-                setError(err.response?.data);
+                setError(apiErrorMessage(err));
             }
         }
     };
@@ -83,6 +101,7 @@ const HomePage = () => {
                 {
                     roomCode: roomCode,
                     playerId: name,
+                    displayName: name,
                 },
                 {
                     headers: {
@@ -94,18 +113,13 @@ const HomePage = () => {
             localStorage.setItem("isHost", "false");
             navigate(`/room/${res.data.roomCode}/lobby`, { state: name });
         } catch (err) {
-            if (axios.isAxiosError(err) && err.response?.status === 400) {
-                setError(err.response?.data);
-                setErrorTitle("Error code: 400");
-            } else if (axios.isAxiosError(err) && err.response?.status === 404) {
-                setError(err.response?.data);
-                setErrorTitle("Error code: 404");
-            } else if (axios.isAxiosError(err) && err.response?.status === 500) {
-                setError(err.response?.data);
-                setErrorTitle("Error code: 500");
-            } else if (axios.isAxiosError(err) && err.response?.status === 409) {
-                setError(err.response?.data);
-                setErrorTitle("Error code: 409");
+            if (axios.isAxiosError(err)) {
+                const code = apiError(err)?.error;
+                setError(apiErrorMessage(err));
+                setErrorTitle(
+                    (code && joinErrorTitles[code]) ??
+                        `Error code: ${err.response?.status ?? "network"}`,
+                );
             }
             console.error(err);
             localStorage.removeItem("roomCode");
@@ -65,7 +65,7 @@ const RoomLobby = () => {
                 navigate(`/room/${code}`, { state: playerName });
             }
             if (msg.type === "new-player" && isHost) {
-                setPlayersList((prev) => [...prev, msg.data]);
+                setPlayersList((prev) => [...prev, msg.data.displayName]);
             }
         };
 
@@ -1,13 +1,12 @@
 import { useLocation, useNavigate } from "react-router-dom";
-
-type Scoreboard = Record<string, number>;
+import type { ScoreEntry } from "./GamePage";
 
 const ScoreboardPage = () => {
     const navigate = useNavigate();
     const location = useLocation();
-    const scoreboard = location.state as Scoreboard | null;
+    const scoreboard = location.state as ScoreEntry[] | null;
 
-    if (!scoreboard || Object.keys(scoreboard).length === 0) {
+    if (!scoreboard || scoreboard.length === 0) {
         return (
             <div className="min-h-screen flex items-center justify-center bg-gradient-to-b from-emerald-300 via-gray-200 to-emerald-100">
                 <p className="text-red-600 text-lg font-medium">
@@ -25,21 +24,19 @@ const ScoreboardPage = () => {
                 </h1>
 
                 <ul className="space-y-3">
-                    {Object.entries(scoreboard)
-                        .sort(([, a], [, b]) => b - a)
-                        .map(([playerId, score], index) => (
-                            <li
-                                key={playerId}
-                                className="flex justify-between items-center px-4 py-3 bg-indigo-50 border border-indigo-200 rounded-lg"
-                            >
-                                <span className="font-medium text-indigo-700">
-                                    #{index + 1} {playerId}
-                                </span>
-                                <span className="text-indigo-600 font-semibold">
-                                    {score} pts
-                                </span>
-                            </li>
-                        ))}
+                    {scoreboard.map((entry, index) => (
+                        <li
+                            key={entry.playerId}
+                            className="flex justify-between items-center px-4 py-3 bg-indigo-50 border border-indigo-200 rounded-lg"
+                        >
+                            <span className="font-medium text-indigo-700">
+                                #{index + 1} {entry.displayName}
+                            </span>
+                            <span className="text-indigo-600 font-semibold">
+                                {entry.score} pts
+                            </span>
+                        </li>
+                    ))}
                 </ul>
 
                 <div className="mt-6 text-center">